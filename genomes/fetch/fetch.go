@@ -0,0 +1,130 @@
+// Package fetch downloads genome files from the NCBI ftp site,
+// which is served over HTTPS since NCBI deprecated plain FTP.
+package fetch
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/kussell-lab/ncbiftp/genomes/reports"
+)
+
+// Fetcher downloads genome files of strains.
+type Fetcher struct {
+	// Client is the HTTP client used for requests,
+	// if nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// BaseURL, if not empty, replaces the scheme and host
+	// of the NCBI URLs, e.g. to fetch from a local server in tests.
+	BaseURL string
+
+	// Retry, if not nil, decides whether a failed request is retried.
+	Retry RetryPolicy
+
+	// Limiter, if not nil, limits the rate of requests, including retries.
+	Limiter *RateLimiter
+}
+
+// FetchGenome downloads the genome FASTA of the replicon accession of the strain,
+// and returns its content, decompressed if the file is gzipped.
+func (f *Fetcher) FetchGenome(ctx context.Context, s reports.Strain, acc string) ([]byte, error) {
+	return f.fetch(ctx, s.GenomeURL(acc))
+}
+
+// fetch downloads the content of the URL,
+// retrying failed attempts by the retry policy.
+func (f *Fetcher) fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	if rawURL == "" {
+		return nil, fmt.Errorf("fetch: no URL")
+	}
+	u, err := f.resolve(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		if f.Limiter != nil {
+			if err := f.Limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		b, err := f.get(ctx, u)
+		if err == nil {
+			return b, nil
+		}
+		if f.Retry == nil || ctx.Err() != nil {
+			return nil, err
+		}
+		wait, retry := f.Retry.Backoff(attempt, err)
+		if !retry {
+			return nil, err
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// get downloads the content of the URL in a single attempt.
+func (f *Fetcher) get(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: u, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	var r io.Reader = resp.Body
+	if strings.HasSuffix(u, ".gz") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s: %w", u, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", u, err)
+	}
+	return b, nil
+}
+
+// resolve replaces the scheme and host of the URL with the BaseURL.
+func (f *Fetcher) resolve(rawURL string) (string, error) {
+	if f.BaseURL == "" {
+		return rawURL, nil
+	}
+
+	base, err := url.Parse(f.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = base.Scheme
+	u.Host = base.Host
+	u.Path = strings.TrimRight(base.Path, "/") + u.Path
+	return u.String(), nil
+}
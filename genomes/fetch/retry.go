@@ -0,0 +1,140 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StatusError is returned when the server responds with a non-OK status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("fetch %s: %s", e.URL, e.Status)
+}
+
+// Temporary returns true if the status is a transient server error,
+// or the server asks to slow down.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// RetryPolicy decides whether a failed request is retried.
+type RetryPolicy interface {
+	// Backoff returns how long to wait before the next attempt,
+	// after the attempt (starting from 1) failed with err,
+	// and false if the request should not be retried.
+	Backoff(attempt int, err error) (wait time.Duration, retry bool)
+}
+
+// ExponentialBackoff retries transient errors
+// with a doubling wait between attempts.
+type ExponentialBackoff struct {
+	Attempts int           // maximum number of attempts.
+	Initial  time.Duration // wait after the first attempt.
+	Max      time.Duration // maximum wait, unlimited if zero.
+}
+
+// Backoff implements the RetryPolicy.
+func (b ExponentialBackoff) Backoff(attempt int, err error) (time.Duration, bool) {
+	if attempt >= b.Attempts || !isTemporary(err) {
+		return 0, false
+	}
+
+	wait := b.Initial
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if b.Max > 0 && wait > b.Max {
+			break
+		}
+	}
+	if b.Max > 0 && wait > b.Max {
+		wait = b.Max
+	}
+	return wait, true
+}
+
+// isTemporary returns true for transient server errors and connection errors,
+// but not for other HTTP statuses or a cancelled context.
+func isTemporary(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Temporary()
+	}
+	return true
+}
+
+// RateLimiter is a token bucket limiting the request rate.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second.
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter allowing rps requests per second,
+// with bursts of at most burst requests.
+// A rps <= 0 does not limit the requests.
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rps, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Wait blocks until a request is allowed, or the context is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.rate <= 0 {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	// take a token, which may reserve a future one.
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	if err := sleep(ctx, wait); err != nil {
+		// give back the reserved token.
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// sleep waits for the duration, or returns early when the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
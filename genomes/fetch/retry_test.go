@@ -0,0 +1,34 @@
+package fetch
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	for _, rps := range []float64{0, -1} {
+		l := NewRateLimiter(rps, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		for i := 0; i < 100; i++ {
+			if err := l.Wait(ctx); err != nil {
+				t.Fatalf("rps %v: wait %d: %v", rps, i, err)
+			}
+		}
+		cancel()
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(100, 1)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first request is allowed by the burst, the next two wait 10ms each.
+	if d := time.Since(start); d < 15*time.Millisecond {
+		t.Errorf("3 requests at 100 rps took %v", d)
+	}
+}
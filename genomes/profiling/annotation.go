@@ -0,0 +1,36 @@
+package profiling
+
+import "github.com/kussell-lab/biogo/feat/gff"
+
+// GeneInfo contains the annotation of a profiled gene,
+// read from the attributes of its GFF record.
+type GeneInfo struct {
+	Name     string // name of the gene in the profile, see Pos.Gene.
+	ID       string // GFF ID.
+	LocusTag string // locus tag, e.g. b0001.
+	Gene     string // gene symbol, e.g. thrL.
+	Product  string // product description, e.g. thr operon leader peptide.
+}
+
+// GeneAnnotations returns the annotation of each gene profiled
+// by ProfileGenomeWithOptions with the same arguments, keyed by the gene name.
+// The Stats of the options are not updated.
+func GeneAnnotations(genome []byte, gffRecords []*gff.Record, opts Options) map[string]GeneInfo {
+	opts.Stats = nil
+	infos := make(map[string]GeneInfo)
+	for _, g := range gffGenes(genome, gffRecords, opts) {
+		info := GeneInfo{
+			Name:     g.name,
+			ID:       gffAttribute(g.rec, "ID"),
+			LocusTag: gffAttribute(g.rec, "locus_tag"),
+			Gene:     gffAttribute(g.rec, "gene"),
+			Product:  gffAttribute(g.rec, "product"),
+		}
+		if info.Gene == "" {
+			// GTF files name the gene symbol gene_name.
+			info.Gene = gffAttribute(g.rec, "gene_name")
+		}
+		infos[g.name] = info
+	}
+	return infos
+}
@@ -0,0 +1,63 @@
+package profiling
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// bedColors are the itemRgb colors of the position types in BED files.
+var bedColors = map[byte]string{
+	FirstPos:         "31,119,180",
+	SecondPos:        "255,127,14",
+	ThirdPos:         "44,160,44",
+	FourFold:         "214,39,40",
+	Undefined:        "127,127,127",
+	Coding:           "148,103,189",
+	ZeroFold:         "140,86,75",
+	TwoFold:          "227,119,194",
+	ThreeFold:        "188,189,34",
+	StartCodon:       "23,190,207",
+	StopCodon:        "0,0,0",
+	AntisenseOverlap: "199,199,199",
+	RNA:              "174,199,232",
+}
+
+// WriteProfileBED writes the profile in the BED format, e.g. for IGV or the UCSC genome browser,
+// with an interval for each run of consecutive positions of the same type and strand,
+// named by the type and colored by the type.
+// NonCoding positions are not written.
+func WriteProfileBED(w io.Writer, profile []Pos, chrom string) error {
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, "track name=%q itemRgb=On\n", chrom+" profile"); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(profile); {
+		end := start + 1
+		for end < len(profile) && profile[end].Type == profile[start].Type && profile[end].Strand == profile[start].Strand {
+			end++
+		}
+
+		p := profile[start]
+		if p.Type != NonCoding {
+			strand := "."
+			if p.Strand != 0 {
+				strand = string(p.Strand)
+			}
+			color, found := bedColors[p.Type]
+			if !found {
+				color = "0,0,0"
+			}
+			// BED intervals are 0-based and half-open.
+			if _, err := fmt.Fprintf(bw, "%s\t%d\t%d\t%s\t0\t%s\t%d\t%d\t%s\n",
+				chrom, start, end, ProfileType(p.Type), strand, start, end, color); err != nil {
+				return err
+			}
+		}
+
+		start = end
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,169 @@
+package profiling
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kussell-lab/biogo/feat/gff"
+	"github.com/kussell-lab/biogo/seq"
+	"github.com/kussell-lab/ncbiftp/taxonomy"
+)
+
+// FindInternalStops returns the indices of the codons (0-based, in the transcription direction)
+// of the GFF record that are stop codons before its final codon,
+// which indicate a pseudogene or a wrong reading frame.
+func FindInternalStops(genome []byte, rec *gff.Record, gc *taxonomy.GeneticCode) (stops []int) {
+	g, ok := recordGene(genome, rec)
+	if !ok {
+		return nil
+	}
+
+	nucl, phase := g.sequence(genome)
+	codons := nucl[phase:]
+	for i := 0; (i+2)*3 <= len(codons); i++ {
+		if gc.IsStopCodon(string(codons[i*3 : (i+1)*3])) {
+			stops = append(stops, i)
+		}
+	}
+
+	return
+}
+
+// CodonIterator returns a function that yields each complete in-frame codon of the GFF record
+// in the transcription direction, with the genome index (0-based) of its first base
+// and its amino acid ('X' if the codon is ambiguous).
+// The codons of a negative strand gene are reverse-complemented,
+// so that the first base of a codon is its highest index.
+// The function returns false after the last codon,
+// or at once if the record falls outside the genome.
+func CodonIterator(genome []byte, rec *gff.Record, gc *taxonomy.GeneticCode) func() (codon string, pos int, aa byte, ok bool) {
+	g, found := recordGene(genome, rec)
+	if !found {
+		return func() (string, int, byte, bool) { return "", 0, 0, false }
+	}
+
+	nucl, j := g.sequence(genome)
+	return func() (codon string, pos int, aa byte, ok bool) {
+		if j+3 > len(nucl) {
+			return "", 0, 0, false
+		}
+		codon = string(nucl[j : j+3])
+		if g.reverse {
+			pos = g.indices[len(g.indices)-1-j]
+		} else {
+			pos = g.indices[j]
+		}
+		aa = gc.Translate(nucl[j : j+3])[0]
+		j += 3
+		return codon, pos, aa, true
+	}
+}
+
+// PositionCodon returns the codon, in the transcription direction,
+// of the coding position at the genome index (0-based) of the profile.
+// The strand of the gene is found from the codon positions around the index.
+// It returns false if the position is not a FirstPos, SecondPos, ThirdPos or FourFold site,
+// or its codon is not complete in the profile, e.g. when profiled with Degeneracy.
+func PositionCodon(profile []Pos, index int) (codon string, ok bool) {
+	if index < 0 || index >= len(profile) {
+		return "", false
+	}
+	pos := codonPosition(profile[index].Type)
+	if pos == 0 {
+		return "", false
+	}
+
+	// the position of codon position k (1, 2 or 3) on each strand.
+	forward := func(k int) int { return index + k - pos }
+	reverse := func(k int) int { return index + pos - k }
+	for _, at := range []func(int) int{forward, reverse} {
+		bases := make([]byte, 3)
+		ok = true
+		for k := 1; k <= 3 && ok; k++ {
+			i := (at(k) + len(profile)) % len(profile)
+			p := profile[i]
+			ok = codonPosition(p.Type) == k && p.Gene == profile[index].Gene && p.Genes == nil
+			bases[k-1] = p.Base
+		}
+		if ok {
+			bases = bytes.ToUpper(bases)
+			if at(1) > at(3) {
+				bases = seq.Complement(bases)
+			}
+			return string(bases), true
+		}
+	}
+
+	return "", false
+}
+
+// codonPosition returns the position (1, 2 or 3) in the codon of a site type,
+// or 0 if the type does not tell the position.
+func codonPosition(t byte) int {
+	switch t {
+	case FirstPos:
+		return 1
+	case SecondPos:
+		return 2
+	case ThirdPos, FourFold:
+		return 3
+	}
+	return 0
+}
+
+// CodonUsage counts each codon in the coding sequences of the GFF records,
+// in the transcription direction and from the first complete codon.
+// Partial and ambiguous codons are not counted.
+func CodonUsage(genome []byte, gffRecords []*gff.Record, gc *taxonomy.GeneticCode) (usage map[string]int) {
+	usage = make(map[string]int)
+	for _, g := range gffGenes(genome, gffRecords, Options{}) {
+		nucl, phase := g.sequence(genome)
+		for j := phase; j+3 <= len(nucl); j += 3 {
+			codon := nucl[j : j+3]
+			if isUnambiguous(codon) {
+				usage[string(codon)]++
+			}
+		}
+	}
+	return
+}
+
+// RSCU returns the relative synonymous codon usage of each codon of the genetic code,
+// the count of the codon divided by the mean count of the codons of its amino acid.
+// Codons of an amino acid that is never used have a RSCU of 0.
+func RSCU(usage map[string]int, gc *taxonomy.GeneticCode) (rscu map[string]float64) {
+	totals := make(map[byte]int)
+	families := make(map[byte]int)
+	for codon, aa := range gc.Table {
+		totals[aa] += usage[codon]
+		families[aa]++
+	}
+
+	rscu = make(map[string]float64)
+	for codon, aa := range gc.Table {
+		if totals[aa] > 0 {
+			rscu[codon] = float64(usage[codon]) * float64(families[aa]) / float64(totals[aa])
+		} else {
+			rscu[codon] = 0
+		}
+	}
+	return
+}
+
+// recordGene returns the coding region of a single GFF record,
+// where a record whose end is before its start wraps around the origin.
+// It returns false if the record falls outside the genome.
+func recordGene(genome []byte, rec *gff.Record) (g cds, ok bool) {
+	indices := codingIndices(rec.Start, rec.End, len(genome), true)
+	if indices == nil {
+		return
+	}
+
+	g = cds{
+		name:    fmt.Sprintf("%s:%d-%d", rec.SeqName, rec.Start, rec.End),
+		indices: indices,
+		reverse: rec.Strand == gff.ReverseStrand,
+		phase:   int(rec.Frame),
+	}
+	return g, true
+}
@@ -0,0 +1,177 @@
+package profiling
+
+import "sort"
+
+// compactTypes are the position types stored in 4 bits by a CompactProfile,
+// the last code marks a type stored in the exceptions.
+var compactTypes = []byte{
+	NonCoding, FirstPos, SecondPos, ThirdPos, FourFold, Undefined, Coding,
+	ZeroFold, TwoFold, ThreeFold, StartCodon, StopCodon, AntisenseOverlap, RNA,
+}
+
+const compactException = 15
+
+// compactBases are the bases stored in 2 bits by a CompactProfile.
+const compactBases = "ACGT"
+
+// CompactProfile is a genomic position profile taking less than one byte per position:
+// the type is stored in 4 bits, the base in 2 bits and its case in 1 bit,
+// and the genes and strands are stored for runs of positions.
+// Other bases (ambiguous) and types are stored in exception maps.
+// The AminoAcid of positions is not kept.
+type CompactProfile struct {
+	length int
+	types  []byte // two types per byte.
+	bases  []byte // four bases per byte.
+	lower  []byte // eight bits per byte, set for a lowercase (soft-masked) base.
+
+	typeExceptions map[int]byte
+	baseExceptions map[int]byte
+
+	genes []string     // gene table, indexed by GeneID.
+	runs  []compactRun // runs of positions of the same genes and strand.
+}
+
+// compactRun is a run of positions of the same genes and strand,
+// from start to the start of the next run.
+type compactRun struct {
+	start    int
+	gene     GeneID
+	strand   byte
+	overlaps []GeneID // see Pos.Genes.
+}
+
+// NewCompactProfile returns the CompactProfile of a profile.
+func NewCompactProfile(profile []Pos) *CompactProfile {
+	c := &CompactProfile{
+		length:         len(profile),
+		types:          make([]byte, (len(profile)+1)/2),
+		bases:          make([]byte, (len(profile)+3)/4),
+		lower:          make([]byte, (len(profile)+7)/8),
+		typeExceptions: make(map[int]byte),
+		baseExceptions: make(map[int]byte),
+		genes:          []string{""},
+	}
+
+	typeCodes := make(map[byte]byte)
+	for code, t := range compactTypes {
+		typeCodes[t] = byte(code)
+	}
+	geneIndex := map[string]GeneID{"": 0}
+	id := func(name string) GeneID {
+		if i, found := geneIndex[name]; found {
+			return i
+		}
+		i := GeneID(len(c.genes))
+		c.genes = append(c.genes, name)
+		geneIndex[name] = i
+		return i
+	}
+
+	for i, p := range profile {
+		code, found := typeCodes[p.Type]
+		if !found {
+			code = compactException
+			c.typeExceptions[i] = p.Type
+		}
+		c.types[i/2] |= code << (4 * uint(i%2))
+
+		base := p.Base
+		if 'a' <= base && base <= 'z' {
+			base -= 'a' - 'A'
+		}
+		k := -1
+		for j := 0; j < len(compactBases); j++ {
+			if base == compactBases[j] {
+				k = j
+			}
+		}
+		if k >= 0 && base != p.Base {
+			c.lower[i/8] |= 1 << uint(i%8)
+		}
+		if k < 0 {
+			k = 0
+			c.baseExceptions[i] = p.Base
+		}
+		c.bases[i/4] |= byte(k) << (2 * uint(i%4))
+
+		if i == 0 || !sameGenes(profile[i-1], p) || profile[i-1].Strand != p.Strand {
+			run := compactRun{start: i, gene: id(p.Gene), strand: p.Strand}
+			if p.Genes != nil {
+				run.overlaps = make([]GeneID, len(p.Genes))
+				for j, g := range p.Genes {
+					run.overlaps[j] = id(g)
+				}
+			}
+			c.runs = append(c.runs, run)
+		}
+	}
+
+	return c
+}
+
+// Len returns the number of positions.
+func (c *CompactProfile) Len() int {
+	return c.length
+}
+
+// TypeAt returns the type of the i-th position.
+func (c *CompactProfile) TypeAt(i int) byte {
+	code := c.types[i/2] >> (4 * uint(i%2)) & 0xf
+	if code == compactException {
+		return c.typeExceptions[i]
+	}
+	return compactTypes[code]
+}
+
+// BaseAt returns the base of the i-th position.
+func (c *CompactProfile) BaseAt(i int) byte {
+	if b, found := c.baseExceptions[i]; found {
+		return b
+	}
+	b := compactBases[c.bases[i/4]>>(2*uint(i%4))&0x3]
+	if c.lower[i/8]>>uint(i%8)&1 == 1 {
+		b += 'a' - 'A'
+	}
+	return b
+}
+
+// GeneAt returns the gene of the i-th position,
+// or an empty string if the position is non-coding.
+func (c *CompactProfile) GeneAt(i int) string {
+	return c.genes[c.run(i).gene]
+}
+
+// run returns the run of the i-th position.
+func (c *CompactProfile) run(i int) compactRun {
+	k := sort.Search(len(c.runs), func(k int) bool { return c.runs[k].start > i })
+	return c.runs[k-1]
+}
+
+// Profile returns the positions as a []Pos profile, without their AminoAcid.
+func (c *CompactProfile) Profile() (profile []Pos) {
+	profile = make([]Pos, c.length)
+	for k, run := range c.runs {
+		end := c.length
+		if k+1 < len(c.runs) {
+			end = c.runs[k+1].start
+		}
+		var genes []string
+		if run.overlaps != nil {
+			genes = make([]string, len(run.overlaps))
+			for j, g := range run.overlaps {
+				genes[j] = c.genes[g]
+			}
+		}
+		for i := run.start; i < end; i++ {
+			profile[i] = Pos{
+				Base:   c.BaseAt(i),
+				Type:   c.TypeAt(i),
+				Gene:   c.genes[run.gene],
+				Strand: run.strand,
+				Genes:  genes,
+			}
+		}
+	}
+	return
+}
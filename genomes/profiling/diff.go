@@ -0,0 +1,82 @@
+package profiling
+
+import "fmt"
+
+// ProfileDiff is a position whose type or gene differs between two profiles.
+type ProfileDiff struct {
+	Index            int // genome index (0-based) of the position.
+	OldType, NewType byte
+	OldGene, NewGene string
+}
+
+// DiffProfiles returns the positions whose Type or Gene differ
+// from the profile a to the profile b, e.g. two annotation versions of a genome.
+// It returns an error if the profiles are not of the same length.
+func DiffProfiles(a, b []Pos) (diffs []ProfileDiff, err error) {
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("profiling: can not diff profiles of lengths %d and %d", len(a), len(b))
+	}
+
+	for i := range a {
+		if a[i].Type != b[i].Type || a[i].Gene != b[i].Gene {
+			diffs = append(diffs, ProfileDiff{
+				Index:   i,
+				OldType: a[i].Type,
+				NewType: b[i].Type,
+				OldGene: a[i].Gene,
+				NewGene: b[i].Gene,
+			})
+		}
+	}
+
+	return
+}
+
+// MergeProfiles returns the primary profile,
+// with its NonCoding positions taken from the secondary profile,
+// e.g. to fill the gaps of a RefSeq annotation with another annotation.
+// It returns an error if the profiles are not of the same length.
+func MergeProfiles(primary, secondary []Pos) (merged []Pos, err error) {
+	if len(primary) != len(secondary) {
+		return nil, fmt.Errorf("profiling: can not merge profiles of lengths %d and %d", len(primary), len(secondary))
+	}
+
+	merged = make([]Pos, len(primary))
+	for i := range primary {
+		merged[i] = primary[i]
+		if primary[i].Type == NonCoding {
+			merged[i] = secondary[i]
+		}
+	}
+
+	return merged, nil
+}
+
+// CommonFourFold returns the genome indices (0-based) of the positions
+// that are FourFold in all the profiles, e.g. of aligned strain genomes.
+// It returns nil if no profile is given,
+// and an error if the profiles are not of the same length.
+func CommonFourFold(profiles ...[]Pos) (indices []int, err error) {
+	if len(profiles) == 0 {
+		return nil, nil
+	}
+	for _, p := range profiles[1:] {
+		if len(p) != len(profiles[0]) {
+			return nil, fmt.Errorf("profiling: can not compare profiles of lengths %d and %d", len(profiles[0]), len(p))
+		}
+	}
+
+	for i := range profiles[0] {
+		common := true
+		for _, p := range profiles {
+			if p[i].Type != FourFold {
+				common = false
+				break
+			}
+		}
+		if common {
+			indices = append(indices, i)
+		}
+	}
+	return
+}
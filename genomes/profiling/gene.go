@@ -0,0 +1,322 @@
+package profiling
+
+import (
+	"github.com/kussell-lab/biogo/feat/gff"
+	"github.com/kussell-lab/biogo/seq"
+)
+
+// GeneProfile returns the positions of the gene in the profile,
+// including positions it shares with overlapping genes.
+// The positions of a gene wrapping around the origin
+// are concatenated from its start to its end.
+func GeneProfile(profile []Pos, geneID string) (gene []Pos) {
+	if geneID == "" {
+		return
+	}
+
+	var indices []int
+	for i, p := range profile {
+		if p.hasGene(geneID) {
+			indices = append(indices, i)
+		}
+	}
+
+	return genePositions(profile, indices)
+}
+
+// geneIndices returns the genome indices of each gene of the profile, in increasing order,
+// including the positions it shares with overlapping genes.
+// The profile is scanned once, instead of once for every gene with GeneProfile.
+func geneIndices(profile []Pos) map[string][]int {
+	genes := make(map[string][]int)
+	add := func(g string, i int) {
+		indices := genes[g]
+		if n := len(indices); n > 0 && indices[n-1] == i {
+			return
+		}
+		genes[g] = append(indices, i)
+	}
+
+	for i, p := range profile {
+		if p.Genes == nil {
+			if p.Gene != "" {
+				add(p.Gene, i)
+			}
+			continue
+		}
+		for _, g := range p.Genes {
+			add(g, i)
+		}
+	}
+	return genes
+}
+
+// genePositions returns the positions of the gene from its genome indices in increasing order,
+// concatenated from its start to its end if it wraps around the origin.
+func genePositions(profile []Pos, indices []int) (gene []Pos) {
+	// if the gene wraps around the origin,
+	// it starts after the gap in its indices.
+	start := 0
+	if len(indices) > 0 && indices[0] == 0 && indices[len(indices)-1] == len(profile)-1 {
+		for k := 1; k < len(indices); k++ {
+			if indices[k] != indices[k-1]+1 {
+				start = k
+				break
+			}
+		}
+	}
+
+	for k := range indices {
+		gene = append(gene, profile[indices[(start+k)%len(indices)]])
+	}
+
+	return
+}
+
+// hasGene returns true if the position belongs to the gene.
+func (p Pos) hasGene(geneID string) bool {
+	if p.Gene == geneID {
+		return true
+	}
+	for _, g := range p.Genes {
+		if g == geneID {
+			return true
+		}
+	}
+	return false
+}
+
+// CodingBase returns the base of the position on the coding strand of its gene,
+// i.e. the complement of Base if the gene is on the negative strand.
+func (p Pos) CodingBase() byte {
+	if p.Strand == '-' {
+		return seq.Complement([]byte{p.Base})[0]
+	}
+	return p.Base
+}
+
+// Lookup returns the position of the profile at the genome index (0-based),
+// i.e. at the 1-based GFF coordinate index+1,
+// and false if the index is out of the profile.
+func Lookup(profile []Pos, index int) (Pos, bool) {
+	if index < 0 || index >= len(profile) {
+		return Pos{}, false
+	}
+	return profile[index], true
+}
+
+// LookupMany returns the positions of the profile at the genome indices (0-based),
+// see Lookup. The position of an index out of the profile is the zero Pos,
+// whose Type is 0.
+func LookupMany(profile []Pos, indices []int) []Pos {
+	positions := make([]Pos, len(indices))
+	for k, i := range indices {
+		positions[k], _ = Lookup(profile, i)
+	}
+	return positions
+}
+
+// StrandProfile returns the positions of the GFF record in the transcription direction,
+// so that the codon positions of a negative strand gene read from 5' to 3'.
+// The bases of a negative strand gene are complemented.
+func StrandProfile(profile []Pos, rec *gff.Record) (gene []Pos) {
+	indices := codingIndices(rec.Start, rec.End, len(profile), true)
+	if indices == nil {
+		return nil
+	}
+
+	gene = make([]Pos, len(indices))
+	for j, i := range indices {
+		gene[j] = profile[i]
+	}
+
+	if rec.Strand == gff.ReverseStrand {
+		bases := make([]byte, len(gene))
+		for j := range gene {
+			bases[j] = gene[len(gene)-1-j].Base
+		}
+		bases = seq.Complement(bases)
+		reversed := make([]Pos, len(gene))
+		for j := range gene {
+			reversed[j] = gene[len(gene)-1-j]
+			reversed[j].Base = bases[j]
+		}
+		gene = reversed
+	}
+
+	return
+}
+
+// Region is a range of positions of a profile.
+type Region struct {
+	Start, End int // genome indices (0-based) of the first and last positions.
+	Length     int
+
+	// Upstream and Downstream are the genes flanking the region on the positive strand,
+	// at Start-1 and End+1. They are empty at the ends of the genome.
+	Upstream, Downstream string
+}
+
+// IntergenicRegions returns the runs of NonCoding positions of the profile,
+// with their flanking genes.
+func IntergenicRegions(profile []Pos) (regions []Region) {
+	for start := 0; start < len(profile); {
+		if profile[start].Type != NonCoding {
+			start++
+			continue
+		}
+		end := start
+		for end+1 < len(profile) && profile[end+1].Type == NonCoding {
+			end++
+		}
+
+		r := Region{Start: start, End: end, Length: end - start + 1}
+		if start > 0 {
+			r.Upstream = profile[start-1].Gene
+		}
+		if end+1 < len(profile) {
+			r.Downstream = profile[end+1].Gene
+		}
+		regions = append(regions, r)
+
+		start = end + 1
+	}
+	return
+}
+
+// GeneProtein returns the protein of each gene of the profile,
+// from the amino acids of its codons in the transcription direction,
+// including the terminal stop codon ('*').
+// The codons are delimited by the codon positions of the sites,
+// so that the sites before the first codon (a non-zero GFF phase) are skipped,
+// and a codon sharing a base with the previous one (a -1 frameshift) is translated once.
+// The sites of unknown codon position, e.g. Undefined overlapping sites,
+// or sites profiled with Degeneracy, continue the current codon.
+// A codon without amino acid, e.g. at overlapping sites, is 'X',
+// and a final partial codon is not translated.
+func GeneProtein(profile []Pos) (proteins map[string][]byte) {
+	proteins = make(map[string][]byte)
+	for name, indices := range geneIndices(profile) {
+		if !isProteinGene(profile, name, indices) {
+			continue
+		}
+
+		gene := genePositions(profile, indices)
+		if geneStrand(gene) == '-' {
+			reversed := make([]Pos, len(gene))
+			for j := range gene {
+				reversed[j] = gene[len(gene)-1-j]
+			}
+			gene = reversed
+		}
+		proteins[name] = translateSites(gene)
+	}
+	return
+}
+
+// translateSites returns the protein of the sites of a gene in the transcription direction,
+// see GeneProtein.
+func translateSites(gene []Pos) (prot []byte) {
+	// skip the sites before the first codon.
+	for len(gene) > 0 && !isCodingType(gene[0].Type) {
+		gene = gene[1:]
+	}
+
+	k := 0        // number of sites of the current codon.
+	var aa byte   // amino acid of the current codon.
+	var last byte // type of the previous site.
+	for _, p := range gene {
+		// the position (1, 2 or 3) of the site in its codon.
+		pos := codonPosition(p.Type)
+		switch {
+		case (p.Type == StartCodon || p.Type == StopCodon) && p.Type != last:
+			pos = 1
+		case pos == 0:
+			pos = k%3 + 1
+		}
+		last = p.Type
+
+		// a new codon starts, the current one is translated.
+		if pos <= k {
+			prot = append(prot, orX(aa))
+			k, aa = 0, 0
+		}
+		k = pos
+		if aa == 0 {
+			aa = p.AminoAcid
+		}
+	}
+	if k == 3 {
+		prot = append(prot, orX(aa))
+	}
+	return
+}
+
+// isCodingType returns true if the type is of a site of a codon.
+func isCodingType(t byte) bool {
+	switch t {
+	case FirstPos, SecondPos, ThirdPos, FourFold, ZeroFold, TwoFold, ThreeFold, StartCodon, StopCodon:
+		return true
+	}
+	return false
+}
+
+// orX returns the amino acid, or 'X' if it is unknown.
+func orX(aa byte) byte {
+	if aa == 0 {
+		return 'X'
+	}
+	return aa
+}
+
+// isProteinGene returns true if the gene is assigned a position that is not RNA.
+func isProteinGene(profile []Pos, name string, indices []int) bool {
+	for _, i := range indices {
+		if p := profile[i]; p.Gene == name && p.Type != RNA {
+			return true
+		}
+	}
+	return false
+}
+
+// geneStrand returns the strand of a gene from its positions not shared with another gene,
+// or 0 if the strand is unknown.
+func geneStrand(gene []Pos) byte {
+	for _, p := range gene {
+		if p.Genes == nil && p.Strand != 0 {
+			return p.Strand
+		}
+	}
+	return 0
+}
+
+// GeneCoordinates returns the genome indices (0-based) of the first and last positions
+// of each gene of the profile, including the positions it shares with overlapping genes,
+// so that the span of a gene is complete even if its sites are assigned to another gene.
+// The span of a gene wrapping around the origin is the entire genome.
+// The GFF coordinates of a gene are the indices plus one.
+func GeneCoordinates(profile []Pos) map[string][2]int {
+	coords := make(map[string][2]int)
+	add := func(gene string, i int) {
+		c, found := coords[gene]
+		if !found {
+			c = [2]int{i, i}
+		}
+		// the profile is scanned in order, so only the end is extended.
+		c[1] = i
+		coords[gene] = c
+	}
+
+	for i, p := range profile {
+		if p.Genes == nil {
+			if p.Gene != "" {
+				add(p.Gene, i)
+			}
+			continue
+		}
+		for _, g := range p.Genes {
+			add(g, i)
+		}
+	}
+	return coords
+}
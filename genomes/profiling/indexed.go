@@ -0,0 +1,131 @@
+package profiling
+
+import (
+	"github.com/kussell-lab/biogo/feat/gff"
+	"github.com/kussell-lab/ncbiftp/taxonomy"
+)
+
+// GeneID is the index of a gene name in the gene table of an IndexedProfile.
+// The ID 0 is the empty name of non-coding positions.
+type GeneID uint32
+
+// IndexedPos is a position of an IndexedProfile,
+// which references its gene by ID instead of by name,
+// so that it takes 8 bytes instead of the 56 bytes of a Pos.
+type IndexedPos struct {
+	Base      byte
+	Type      byte
+	AminoAcid byte
+	Strand    byte
+	Gene      GeneID
+}
+
+// IndexedProfile is a genomic position profile
+// whose gene names are stored once in a gene table.
+type IndexedProfile struct {
+	Genes     []string         // gene table, indexed by GeneID.
+	Positions []IndexedPos     // positions of the genome.
+	Overlaps  map[int][]GeneID // genes covering each overlapping position, see Pos.Genes.
+}
+
+// ProfileGenomeIndexed is like ProfileGenomeWithOptions,
+// but returns an IndexedProfile, without building the []Pos profile.
+func ProfileGenomeIndexed(genome []byte, gffRecords []*gff.Record, gc *taxonomy.GeneticCode, opts Options) *IndexedProfile {
+	mustCheckGeneticCode(gc, opts)
+	sites := newIndexedStore(len(genome))
+	newSiteWriter(genome, opts, sites).writeGenes(gffRecords, gc)
+	return sites.ip
+}
+
+// IndexProfile returns the IndexedProfile of a profile.
+func IndexProfile(profile []Pos) *IndexedProfile {
+	sites := newIndexedStore(len(profile))
+	for i, p := range profile {
+		sites.setSite(i, p)
+	}
+	return sites.ip
+}
+
+// indexedStore stores the sites written by a geneWriter in an IndexedProfile.
+type indexedStore struct {
+	ip  *IndexedProfile
+	ids map[string]GeneID // ID of each gene name of the gene table.
+}
+
+func newIndexedStore(length int) *indexedStore {
+	return &indexedStore{ip: newIndexedProfile(length), ids: map[string]GeneID{"": 0}}
+}
+
+func (s *indexedStore) site(i int) Pos {
+	return s.ip.Pos(i)
+}
+
+func (s *indexedStore) setSite(i int, p Pos) {
+	s.ip.Positions[i] = IndexedPos{Base: p.Base, Type: p.Type, AminoAcid: p.AminoAcid, Strand: p.Strand, Gene: s.id(p.Gene)}
+	if p.Genes == nil {
+		delete(s.ip.Overlaps, i)
+		return
+	}
+	genes := make([]GeneID, len(p.Genes))
+	for k, g := range p.Genes {
+		genes[k] = s.id(g)
+	}
+	s.ip.Overlaps[i] = genes
+}
+
+// id returns the ID of a gene name, adding it to the gene table if it is new.
+func (s *indexedStore) id(name string) GeneID {
+	if i, found := s.ids[name]; found {
+		return i
+	}
+	i := s.ip.addGene(name)
+	s.ids[name] = i
+	return i
+}
+
+// newIndexedProfile returns an IndexedProfile with all sites marked as non-coding.
+func newIndexedProfile(length int) *IndexedProfile {
+	ip := &IndexedProfile{
+		Genes:     []string{""},
+		Positions: make([]IndexedPos, length),
+		Overlaps:  make(map[int][]GeneID),
+	}
+	for i := range ip.Positions {
+		ip.Positions[i].Type = NonCoding
+	}
+	return ip
+}
+
+// addGene appends a name to the gene table, and returns its ID.
+func (ip *IndexedProfile) addGene(name string) GeneID {
+	ip.Genes = append(ip.Genes, name)
+	return GeneID(len(ip.Genes) - 1)
+}
+
+// GeneName returns the name of the gene of the i-th position,
+// or an empty string if the position is non-coding.
+func (ip *IndexedProfile) GeneName(i int) string {
+	return ip.Genes[ip.Positions[i].Gene]
+}
+
+// Pos returns the i-th position as a Pos.
+func (ip *IndexedProfile) Pos(i int) Pos {
+	p := ip.Positions[i]
+	pos := Pos{Base: p.Base, Type: p.Type, Gene: ip.Genes[p.Gene], AminoAcid: p.AminoAcid, Strand: p.Strand}
+	if genes, found := ip.Overlaps[i]; found {
+		pos.Genes = make([]string, len(genes))
+		for k, g := range genes {
+			pos.Genes[k] = ip.Genes[g]
+		}
+	}
+	return pos
+}
+
+// Profile returns the positions as a []Pos profile.
+func (ip *IndexedProfile) Profile() (profile []Pos) {
+	profile = make([]Pos, len(ip.Positions))
+	for i := range profile {
+		profile[i] = ip.Pos(i)
+	}
+	return
+}
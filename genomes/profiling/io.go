@@ -0,0 +1,361 @@
+package profiling
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// profileMagic starts every encoded profile, followed by the format version.
+const profileMagic = "NCBIPROF"
+
+// profileVersion is the format version written by WriteProfile.
+// Version 1 has no Strand field, which is read as 0.
+const profileVersion = 2
+
+// ErrProfileFormat is returned by ReadProfile when the input is not an encoded profile.
+var ErrProfileFormat = errors.New("profiling: invalid profile format")
+
+// bounds of the lengths read by ReadProfile, above any prokaryote genome,
+// so that a corrupt input is not trusted with allocating them:
+// since a run of constant fields takes a few bytes for any length,
+// a few bytes may declare maxProfileLength positions.
+const (
+	maxProfileLength = 1 << 32
+	maxGeneNameLen   = 1 << 16
+)
+
+// WriteProfile writes the profile in a compact binary encoding.
+//
+// Gene names are stored once in a table,
+// and consecutive positions of the same gene (or of no gene) form a run.
+// For each run, the Type, Base, AminoAcid and Strand values
+// are stored once if they are constant along the run,
+// so that long NonCoding stretches take a few bytes only.
+func WriteProfile(w io.Writer, profile []Pos) error {
+	bw := bufio.NewWriter(w)
+	enc := profileEncoder{w: bw}
+
+	// build the gene table, the empty name is always the first.
+	geneIndex := map[string]uint64{"": 0}
+	genes := []string{""}
+	addGene := func(g string) {
+		if _, found := geneIndex[g]; !found {
+			geneIndex[g] = uint64(len(genes))
+			genes = append(genes, g)
+		}
+	}
+	for _, p := range profile {
+		addGene(p.Gene)
+		for _, g := range p.Genes {
+			addGene(g)
+		}
+	}
+
+	enc.writeBytes([]byte(profileMagic))
+	enc.writeBytes([]byte{profileVersion})
+	enc.writeUvarint(uint64(len(profile)))
+	enc.writeUvarint(uint64(len(genes)))
+	for _, g := range genes {
+		enc.writeUvarint(uint64(len(g)))
+		enc.writeBytes([]byte(g))
+	}
+
+	for start := 0; start < len(profile); {
+		end := start + 1
+		for end < len(profile) && sameGenes(profile[start], profile[end]) {
+			end++
+		}
+		run := profile[start:end]
+
+		enc.writeUvarint(uint64(len(run)))
+		enc.writeUvarint(geneIndex[run[0].Gene])
+		// the number of overlapping genes is shifted by one,
+		// so that a nil Genes is distinct from an empty one.
+		if run[0].Genes == nil {
+			enc.writeUvarint(0)
+		} else {
+			enc.writeUvarint(uint64(len(run[0].Genes)) + 1)
+			for _, g := range run[0].Genes {
+				enc.writeUvarint(geneIndex[g])
+			}
+		}
+		enc.writeField(run, func(p Pos) byte { return p.Type })
+		enc.writeField(run, func(p Pos) byte { return p.Base })
+		enc.writeField(run, func(p Pos) byte { return p.AminoAcid })
+		enc.writeField(run, func(p Pos) byte { return p.Strand })
+
+		start = end
+	}
+
+	if enc.err != nil {
+		return enc.err
+	}
+	return bw.Flush()
+}
+
+// ProfileHash returns the hex-encoded SHA-256 of the encoding of the profile by WriteProfile,
+// which is identical for identical profiles, e.g. to validate cached profiles.
+func ProfileHash(profile []Pos) string {
+	h := sha256.New()
+	// writing to a hash never fails.
+	WriteProfile(h, profile)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ReadProfile reads a profile written by WriteProfile.
+func ReadProfile(r io.Reader) (profile []Pos, err error) {
+	dec := profileDecoder{r: bufio.NewReader(r)}
+
+	magic := dec.readBytes(len(profileMagic) + 1)
+	if dec.err == nil && string(magic[:len(profileMagic)]) != profileMagic {
+		return nil, ErrProfileFormat
+	}
+	var version byte
+	if dec.err == nil {
+		version = magic[len(profileMagic)]
+		if version < 1 || version > profileVersion {
+			return nil, fmt.Errorf("%w: unknown version %d", ErrProfileFormat, version)
+		}
+	}
+
+	length := dec.readUvarint()
+	if dec.err == nil && length > maxProfileLength {
+		return nil, fmt.Errorf("%w: invalid length %d", ErrProfileFormat, length)
+	}
+	ngenes := dec.readUvarint()
+	var genes []string
+	for i := uint64(0); i < ngenes && dec.err == nil; i++ {
+		n := dec.readUvarint()
+		if n > maxGeneNameLen {
+			dec.fail(fmt.Errorf("%w: invalid gene name length %d", ErrProfileFormat, n))
+			break
+		}
+		genes = append(genes, string(dec.readBytes(int(n))))
+	}
+	gene := func(i uint64) string {
+		if i >= uint64(len(genes)) {
+			dec.fail(ErrProfileFormat)
+			return ""
+		}
+		return genes[i]
+	}
+	if dec.err != nil {
+		return nil, dec.err
+	}
+
+	// the length is not verified until the runs are read,
+	// so let the profile grow with them.
+	for uint64(len(profile)) < length && dec.err == nil {
+		n := dec.readUvarint()
+		if n == 0 || uint64(len(profile))+n > length {
+			dec.fail(ErrProfileFormat)
+			break
+		}
+		g := gene(dec.readUvarint())
+		var overlaps []string
+		if m := dec.readUvarint(); m > 0 {
+			overlaps = []string{}
+			for i := uint64(1); i < m && dec.err == nil; i++ {
+				overlaps = append(overlaps, gene(dec.readUvarint()))
+			}
+		}
+		types := dec.readField(int(n))
+		bases := dec.readField(int(n))
+		aas := dec.readField(int(n))
+		strands := func(int) byte { return 0 }
+		if version >= 2 {
+			strands = dec.readField(int(n))
+		}
+		if dec.err != nil {
+			break
+		}
+
+		for i := 0; i < int(n); i++ {
+			profile = append(profile, Pos{
+				Type:      types(i),
+				Base:      bases(i),
+				Gene:      g,
+				AminoAcid: aas(i),
+				Strand:    strands(i),
+				Genes:     overlaps,
+			})
+		}
+	}
+
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	return profile, nil
+}
+
+// sameGenes returns true if the two positions belong to the same genes.
+func sameGenes(a, b Pos) bool {
+	if a.Gene != b.Gene || len(a.Genes) != len(b.Genes) || (a.Genes == nil) != (b.Genes == nil) {
+		return false
+	}
+	for i := range a.Genes {
+		if a.Genes[i] != b.Genes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// profileEncoder writes the encoding, and keeps the first error.
+type profileEncoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *profileEncoder) writeBytes(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *profileEncoder) writeUvarint(x uint64) {
+	n := binary.PutUvarint(e.buf[:], x)
+	e.writeBytes(e.buf[:n])
+}
+
+// writeField writes a flag 0 followed by the single value if the field is constant along the run,
+// otherwise a flag 1 followed by the value of every position.
+func (e *profileEncoder) writeField(run []Pos, field func(Pos) byte) {
+	values := make([]byte, len(run))
+	constant := true
+	for i, p := range run {
+		values[i] = field(p)
+		if values[i] != values[0] {
+			constant = false
+		}
+	}
+
+	if constant {
+		e.writeBytes([]byte{0, values[0]})
+	} else {
+		e.writeBytes([]byte{1})
+		e.writeBytes(values)
+	}
+}
+
+// profileDecoder reads the encoding, and keeps the first error.
+type profileDecoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *profileDecoder) fail(err error) {
+	if d.err == nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		d.err = err
+	}
+}
+
+func (d *profileDecoder) readBytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	// read through a limited reader, instead of allocating n bytes at once,
+	// so that a corrupt length fails at the end of the input.
+	b, err := io.ReadAll(io.LimitReader(d.r, int64(n)))
+	if err != nil {
+		d.fail(err)
+		return nil
+	}
+	if len(b) < n {
+		d.fail(io.ErrUnexpectedEOF)
+		return nil
+	}
+	return b
+}
+
+func (d *profileDecoder) readUvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	x, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.fail(err)
+	}
+	return x
+}
+
+// readField reads a field written by writeField,
+// and returns the value at each position of the run.
+func (d *profileDecoder) readField(n int) func(i int) byte {
+	flag := d.readBytes(1)
+	if d.err != nil {
+		return nil
+	}
+	switch flag[0] {
+	case 0:
+		v := d.readBytes(1)
+		if d.err != nil {
+			return nil
+		}
+		return func(int) byte { return v[0] }
+	case 1:
+		values := d.readBytes(n)
+		if d.err != nil {
+			return nil
+		}
+		return func(i int) byte { return values[i] }
+	}
+	d.fail(fmt.Errorf("%w: unknown field flag %d", ErrProfileFormat, flag[0]))
+	return nil
+}
+
+// ProfileToBytes returns the type of every position of the profile,
+// in the same layout as the PosProfile of a reports.Genome.
+func ProfileToBytes(profile []Pos) []byte {
+	types := make([]byte, len(profile))
+	for i, p := range profile {
+		types[i] = p.Type
+	}
+	return types
+}
+
+// fastaLineWidth is the number of types on a line written by WriteProfileFasta.
+const fastaLineWidth = 80
+
+// WriteProfileFasta writes the types of the profile as a FASTA record,
+// with the accession as its defline, so that it aligns to the genome sequence.
+func WriteProfileFasta(w io.Writer, accession string, profile []Pos) error {
+	return WriteFasta(w, accession, ProfileToBytes(profile), fastaLineWidth)
+}
+
+// WriteFasta writes a sequence as a FASTA record with the id as its defline,
+// wrapping the sequence lines at lineWidth.
+// If lineWidth is not positive, the sequence is written on one line.
+func WriteFasta(w io.Writer, id string, seq []byte, lineWidth int) error {
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, ">%s\n", id); err != nil {
+		return err
+	}
+
+	if lineWidth <= 0 {
+		lineWidth = len(seq)
+	}
+	for start := 0; start < len(seq); start += lineWidth {
+		end := start + lineWidth
+		if end > len(seq) {
+			end = len(seq)
+		}
+		if _, err := bw.Write(seq[start:end]); err != nil {
+			return err
+		}
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,73 @@
+package profiling
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteFastaRoundTrip(t *testing.T) {
+	line := strings.Repeat("ACGT", 15)
+	fasta := ">NC_000913\n" + line + "\n" + line + "\nACGTACGTAC\n"
+
+	sequences, err := readFasta(strings.NewReader(fasta), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteFasta(&buf, sequences[0].Id, sequences[0].Seq, len(line)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != fasta {
+		t.Errorf("WriteFasta wrote\n%s\nwant\n%s", buf.String(), fasta)
+	}
+}
+
+func TestReadProfileRejectsHugeRuns(t *testing.T) {
+	header := func(length uint64) []byte {
+		b := append([]byte(profileMagic), profileVersion)
+		b = binary.AppendUvarint(b, length)
+		// the gene table has only the empty name.
+		b = binary.AppendUvarint(b, 1)
+		return binary.AppendUvarint(b, 0)
+	}
+	// a run of n NonCoding positions of constant fields.
+	run := func(b []byte, n uint64) []byte {
+		b = binary.AppendUvarint(b, n)
+		b = binary.AppendUvarint(b, 0)
+		b = binary.AppendUvarint(b, 0)
+		for _, v := range []byte{NonCoding, 'A', 0, 0} {
+			b = append(b, 0, v)
+		}
+		return b
+	}
+
+	for name, input := range map[string][]byte{
+		"huge length":       run(header(1<<34), 1<<34),
+		"run beyond length": run(header(10), 1<<30),
+		// a length of 10 and one gene, whose name is too long.
+		"huge gene name": binary.AppendUvarint(append([]byte(profileMagic), profileVersion, 10, 1), 1<<63),
+		"run beyond end": run(run(header(10), 6), 6),
+	} {
+		if _, err := ReadProfile(bytes.NewReader(input)); !errors.Is(err, ErrProfileFormat) {
+			t.Errorf("%s: error = %v, want ErrProfileFormat", name, err)
+		}
+	}
+}
+
+func TestReadProfileEmptyGenes(t *testing.T) {
+	profile := []Pos{{Base: 'A', Type: Undefined, Gene: "g", Genes: []string{}}}
+	var buf bytes.Buffer
+	if err := WriteProfile(&buf, profile); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadProfile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Genes == nil {
+		t.Error("empty Genes read as nil")
+	}
+}
@@ -0,0 +1,79 @@
+package profiling
+
+import "sort"
+
+// OverlapInfo describes two genes sharing sites in a profile.
+type OverlapInfo struct {
+	A, B       string // overlapping genes, A profiled before B.
+	Start, End int    // genome indices (0-based) of the first and last shared sites.
+	SameStrand bool   // true if the genes are on the same strand.
+
+	// FrameShift is the offset (0, 1 or 2) of the reading frame of B
+	// from the reading frame of A, in the transcription direction,
+	// e.g. 1 for a +1 and 2 for a -1 frameshift overlap.
+	// It is -1 if the genes are on opposite strands,
+	// or the frame of a gene is unknown.
+	FrameShift int
+}
+
+// Overlaps returns the overlaps of the genes of the profile, ordered by their start.
+// The strand and reading frame of a gene are read from its sites not shared with another gene,
+// so the frame of a gene profiled with Degeneracy,
+// or nested in another gene, is unknown.
+func Overlaps(profile []Pos) (overlaps []OverlapInfo) {
+	strands := make(map[string]byte)
+	frames := make(map[string]int)
+	for i, p := range profile {
+		if p.Genes != nil || p.Gene == "" {
+			continue
+		}
+		strands[p.Gene] = p.Strand
+		if _, found := frames[p.Gene]; found {
+			continue
+		}
+		// the index of the first base of the codon, modulo 3.
+		if k := codonPosition(p.Type); k > 0 {
+			if p.Strand == '-' {
+				frames[p.Gene] = (i + k - 1) % 3
+			} else {
+				frames[p.Gene] = ((i-k+1)%3 + 3) % 3
+			}
+		}
+	}
+
+	type pair struct{ a, b string }
+	index := make(map[pair]int)
+	for i, p := range profile {
+		for j := 1; j < len(p.Genes); j++ {
+			for _, a := range p.Genes[:j] {
+				key := pair{a, p.Genes[j]}
+				if k, found := index[key]; found {
+					overlaps[k].End = i
+					continue
+				}
+				index[key] = len(overlaps)
+				overlaps = append(overlaps, OverlapInfo{A: a, B: p.Genes[j], Start: i, End: i})
+			}
+		}
+	}
+
+	for k := range overlaps {
+		o := &overlaps[k]
+		sa, foundA := strands[o.A]
+		sb, foundB := strands[o.B]
+		o.SameStrand = foundA && foundB && sa == sb
+		o.FrameShift = -1
+		fa, foundA := frames[o.A]
+		fb, foundB := frames[o.B]
+		if o.SameStrand && foundA && foundB {
+			if sa == '-' {
+				o.FrameShift = (fa - fb + 3) % 3
+			} else {
+				o.FrameShift = (fb - fa + 3) % 3
+			}
+		}
+	}
+
+	sort.SliceStable(overlaps, func(i, j int) bool { return overlaps[i].Start < overlaps[j].Start })
+	return
+}
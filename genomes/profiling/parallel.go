@@ -0,0 +1,105 @@
+package profiling
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/kussell-lab/biogo/feat/gff"
+	"github.com/kussell-lab/ncbiftp/taxonomy"
+)
+
+// ProfileGenomeParallel is like ProfileGenome,
+// but profiles the genes concurrently with a number of workers.
+// If workers is not positive, it uses runtime.NumCPU() workers.
+// The genes are written into the genomic profile in the record order,
+// so the result, including overlapping regions, is identical to ProfileGenome.
+func ProfileGenomeParallel(genome []byte, gffRecords []*gff.Record, gc *taxonomy.GeneticCode, workers int) (profile []Pos) {
+	opts := Options{}
+	mustCheckGeneticCode(gc, opts)
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	genes := gffGenes(genome, gffRecords, opts)
+	gps := make([]geneProfile, len(genes))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				gps[i] = profileGene(genome, genes[i], gc, opts)
+			}
+		}()
+	}
+	for i := range genes {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// resolve overlaps serially.
+	w := newGeneWriter(genome, opts)
+	for _, gp := range gps {
+		w.write(gp)
+	}
+
+	return w.profile
+}
+
+// ProfileJob is a genome to be profiled by ProfileGenomes,
+// from a FASTA file and a .ptt file as in ProfileGenomeFromFiles.
+type ProfileJob struct {
+	GenomeFile  string
+	PttFile     string
+	GeneticCode *taxonomy.GeneticCode
+}
+
+// ProfileResult is the profile of a ProfileJob,
+// or the error if the job failed.
+type ProfileResult struct {
+	Job     ProfileJob
+	Profile []Pos
+	Err     error
+}
+
+// ProfileGenomes profiles the genomes of the jobs concurrently with a number of workers.
+// If workers is not positive, it uses runtime.NumCPU() workers.
+// The results are in the job order, and a failed job does not stop the others.
+// If the context is done, the jobs not yet started fail with the context error,
+// which is also returned, and the running jobs stop with it
+// after reading a file or profiling contextCheckGenes genes.
+func ProfileGenomes(ctx context.Context, jobs []ProfileJob, workers int) (results []ProfileResult, err error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results = make([]ProfileResult, len(jobs))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				job := jobs[i]
+				results[i] = ProfileResult{Job: job}
+				if err := ctx.Err(); err != nil {
+					results[i].Err = err
+					continue
+				}
+				results[i].Profile, results[i].Err = profileFilesContext(ctx, job.GenomeFile, job.PttFile, job.GeneticCode)
+			}
+		}()
+	}
+	for i := range jobs {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	return results, ctx.Err()
+}
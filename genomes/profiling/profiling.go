@@ -1,8 +1,14 @@
 package profiling
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
 
 	"github.com/kussell-lab/biogo/feat/gff"
 	"github.com/kussell-lab/biogo/seq"
@@ -12,9 +18,15 @@ import (
 
 // Pos contains genomic position profile.
 type Pos struct {
-	Base byte
-	Type byte
-	Gene string
+	Base      byte
+	Type      byte
+	Gene      string
+	AminoAcid byte // amino acid encoded by the codon, 0 if non-coding or ambiguous.
+	Strand    byte // strand of the gene, '+' or '-', 0 if non-coding.
+
+	// Genes lists every gene covering an overlapping position,
+	// it is nil if the position belongs to at most one gene.
+	Genes []string
 }
 
 // A position could be one of those:
@@ -24,6 +36,14 @@ type Pos struct {
 // 	Fourfold site
 // 	NonCoding site
 // 	Undefined
+// When profiling degeneracy, a coding site is instead one of
+// ZeroFold, TwoFold, ThreeFold or FourFold,
+// by the number of synonymous substitutions at the site.
+// The sites of a start codon may be marked as StartCodon,
+// and those of the terminal stop codon are marked as StopCodon.
+// Overlapping sites are Undefined, or AntisenseOverlap
+// if the overlapping genes are on opposite strands.
+// Non-coding sites of RNA genes may be marked as RNA.
 const (
 	NonCoding byte = '0'
 	FirstPos  byte = '1'
@@ -32,169 +52,1181 @@ const (
 	FourFold  byte = '4'
 	Undefined byte = '5'
 	Coding    byte = '6'
+	ZeroFold  byte = '7'
+	TwoFold   byte = '8'
+	ThreeFold byte = '9'
+
+	StartCodon byte = 'S'
+	StopCodon  byte = 'E'
+
+	AntisenseOverlap byte = 'A'
+	RNA              byte = 'R'
 )
 
+// ProfileType is the type of a position in a profile,
+// e.g. ProfileType(p.Type).String() gives a readable name of the type in logs.
+type ProfileType byte
+
+var profileTypeNames = map[byte]string{
+	NonCoding:  "NonCoding",
+	FirstPos:   "FirstPos",
+	SecondPos:  "SecondPos",
+	ThirdPos:   "ThirdPos",
+	FourFold:   "FourFold",
+	Undefined:  "Undefined",
+	Coding:     "Coding",
+	ZeroFold:   "ZeroFold",
+	TwoFold:    "TwoFold",
+	ThreeFold:  "ThreeFold",
+	StartCodon: "StartCodon",
+	StopCodon:  "StopCodon",
+
+	AntisenseOverlap: "AntisenseOverlap",
+	RNA:              "RNA",
+}
+
+// String returns the name of the constant of the type,
+// or ProfileType('x') for an unknown type.
+func (t ProfileType) String() string {
+	if name, found := profileTypeNames[byte(t)]; found {
+		return name
+	}
+	return fmt.Sprintf("ProfileType(%q)", byte(t))
+}
+
+// Options controls how a genome is profiled.
+type Options struct {
+	// Circular treats the genome as a circular chromosome,
+	// so that a gene whose end is before its start
+	// is profiled as wrapping around the origin,
+	// instead of being skipped.
+	Circular bool
+
+	// Degeneracy classifies every codon position
+	// as ZeroFold, TwoFold, ThreeFold or FourFold,
+	// instead of FirstPos, SecondPos, ThirdPos or FourFold.
+	Degeneracy bool
+
+	// StartCodons marks the sites of the first codon as StartCodon,
+	// if it is a start codon of the genetic code,
+	// and translates it as methionine.
+	StartCodons bool
+
+	// Overlap is how the sites shared by genes are resolved,
+	// marking them as Undefined by default.
+	Overlap OverlapPolicy
+
+	// AntisenseOverlaps marks the sites shared by genes on opposite strands
+	// as AntisenseOverlap, instead of Undefined.
+	AntisenseOverlaps bool
+
+	// FFCodons, if not nil, is the set of fourfold codons,
+	// instead of the FFCodons of the genetic code,
+	// e.g. for a recoded organism. It does not apply with Degeneracy,
+	// which classifies the sites from the translation table.
+	FFCodons map[string]bool
+
+	// GeneFilter, if not nil, selects the GFF records to be profiled,
+	// e.g. the core genes; the sites of the other records are left NonCoding.
+	GeneFilter func(*gff.Record) bool
+
+	// Strand, if '+' or '-', selects the GFF records on the strand,
+	// e.g. to separate the leading and lagging strand genes;
+	// the sites of the other records are left NonCoding.
+	Strand byte
+
+	// Selenoproteins, if not nil, selects the GFF records of selenoproteins,
+	// e.g. IsSelenoprotein; their internal TGA codons encode selenocysteine ('U')
+	// instead of stop, and with Degeneracy their sites are ZeroFold.
+	Selenoproteins func(*gff.Record) bool
+
+	// Offset is subtracted from the GFF coordinates,
+	// when the genome is a subsequence starting at position Offset+1
+	// of the sequence the records are annotated on,
+	// e.g. an extracted prophage region.
+	Offset int
+
+	// Progress, if not nil, is called with the numbers of profiled and of all genes,
+	// every progressGenes genes and after the last gene.
+	Progress func(done, total int)
+
+	// Stats, if not nil, is updated with counts of the profiled genes.
+	Stats *Stats
+}
+
+// OverlapPolicy is how the sites of overlapping genes are resolved.
+// In every policy, the Genes of such a site lists the overlapping genes.
+type OverlapPolicy int
+
+const (
+	// MarkUndefined marks the sites as Undefined.
+	MarkUndefined OverlapPolicy = iota
+	// FirstWins keeps the type, gene and amino acid
+	// of the first gene profiled at the sites.
+	FirstWins
+	// LongestWins assigns the sites to the longest of the genes,
+	// or to the first gene profiled if they are of the same length.
+	LongestWins
+)
+
+// Stats contains counts of the genes in profiling.
+type Stats struct {
+	// PartialGenes is the number of genes whose length is not a multiple of three;
+	// the sites of their final partial codon are marked as Undefined.
+	PartialGenes int
+
+	// SkippedGenes is the number of genes that are not profiled,
+	// because their coordinates are outside the genome,
+	// or they cross the origin of a linear genome.
+	SkippedGenes int
+
+	// AnnotationErrors lists the records whose coordinates are outside the genome,
+	// see ValidateAnnotation.
+	AnnotationErrors []error
+}
+
+// ProfileGenome generates codon position profile for the genome,
+// from the CDS features of the GFF records.
+// It panics if the genetic code is nil or has no fourfold codons.
 func ProfileGenome(genome []byte, gffRecords []*gff.Record, gc *taxonomy.GeneticCode) (profile []Pos) {
+	return ProfileGenomeWithOptions(genome, gffRecords, gc, Options{})
+}
+
+// ProfileGenes is like ProfileGenome,
+// but profiles only the GFF records selected by the filter, see Options.GeneFilter.
+func ProfileGenes(genome []byte, gffRecords []*gff.Record, gc *taxonomy.GeneticCode, geneFilter func(*gff.Record) bool) (profile []Pos) {
+	return ProfileGenomeWithOptions(genome, gffRecords, gc, Options{GeneFilter: geneFilter})
+}
+
+// ProfileGenomeWithOptions is like ProfileGenome,
+// but is controlled by the options.
+func ProfileGenomeWithOptions(genome []byte, gffRecords []*gff.Record, gc *taxonomy.GeneticCode, opts Options) (profile []Pos) {
+	mustCheckGeneticCode(gc, opts)
 
 	// mark all sites as non-coding.
-	profile = make([]Pos, len(genome))
+	w := newGeneWriter(genome, opts)
+	w.writeGenes(gffRecords, gc)
+
+	return w.profile
+}
+
+// writeGenes profiles the genes of the GFF records selected by the options of the writer,
+// and writes them, reporting the progress and the statistics to the options.
+func (w *geneWriter) writeGenes(gffRecords []*gff.Record, gc *taxonomy.GeneticCode) {
+	opts := w.opts
+	if opts.Stats != nil {
+		errs := ValidateAnnotation(len(w.genome), shiftRecords(filterRecords(gffRecords, opts.selects), opts.Offset))
+		opts.Stats.AnnotationErrors = append(opts.Stats.AnnotationErrors, errs...)
+	}
+
+	// for each gene, mark codon positions.
+	genes := gffGenes(w.genome, gffRecords, opts)
+	for i, g := range genes {
+		w.write(profileGene(w.genome, g, gc, opts))
+		if opts.Progress != nil && ((i+1)%progressGenes == 0 || i+1 == len(genes)) {
+			opts.Progress(i+1, len(genes))
+		}
+	}
+}
+
+// progressGenes is the number of genes profiled
+// between two calls of Options.Progress.
+const progressGenes = 100
+
+// contextCheckGenes is the number of genes profiled
+// between two checks of the context in ProfileGenomeContext and ProfileGenomes.
+const contextCheckGenes = 1000
+
+// ProfileGenomeContext is like ProfileGenome,
+// but returns early with the context error if the context is done.
+// The context is checked every contextCheckGenes genes.
+func ProfileGenomeContext(ctx context.Context, genome []byte, gffRecords []*gff.Record, gc *taxonomy.GeneticCode) (profile []Pos, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	opts := Options{}
+	if err := checkGeneticCode(gc, opts); err != nil {
+		return nil, err
+	}
+	w := newGeneWriter(genome, opts)
+	for i, g := range gffGenes(genome, gffRecords, opts) {
+		if i%contextCheckGenes == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		w.write(profileGene(genome, g, gc, opts))
+	}
+
+	return w.profile, nil
+}
+
+// checkGeneticCode returns an error if the genetic code can not be used for profiling,
+// since a missing table or fourfold codons would silently misclassify every site.
+func checkGeneticCode(gc *taxonomy.GeneticCode, opts Options) error {
+	switch {
+	case gc == nil:
+		return errors.New("profiling: nil genetic code")
+	case gc.Table == nil:
+		return fmt.Errorf("profiling: genetic code %s has no translation table", gc.Id)
+	case gc.FFCodons == nil && opts.FFCodons == nil:
+		return fmt.Errorf("profiling: genetic code %s has no fourfold codons", gc.Id)
+	}
+	return nil
+}
+
+// mustCheckGeneticCode is like checkGeneticCode, but panics on the error.
+func mustCheckGeneticCode(gc *taxonomy.GeneticCode, opts Options) {
+	if err := checkGeneticCode(gc, opts); err != nil {
+		panic(err)
+	}
+}
+
+// newProfile returns a profile with all sites marked as non-coding.
+func newProfile(length int) (profile []Pos) {
+	profile = make([]Pos, length)
 	for i := 0; i < len(profile); i++ {
 		profile[i] = Pos{Type: NonCoding}
 	}
+	return
+}
 
-	// for each gene, mark codon positions.
-	geneIndex := 0
-	for _, rec := range gffRecords {
-		geneIndex++
-		// prepare nucleotide sequence,
-		// we need it for determine 4-fold codons.
-		var nucl []byte
-		if rec.End >= rec.Start {
-			nucl = genome[rec.Start-1 : rec.End]
-		} else {
-			// skip genes across boundary.
+// gffGenes returns the coding regions of the GFF records to be profiled.
+// The segments of a joined CDS (ribosomal frameshifts, etc.)
+// are records sharing the same ID, and are profiled as one gene.
+// A gene is named by its GFF locus_tag or ID attribute, if it is unique,
+// otherwise by its SeqName and the index (1-based) of its first record.
+func gffGenes(genome []byte, gffRecords []*gff.Record, opts Options) (genes []cds) {
+	var groups [][]*gff.Record
+	var geneIndices []int
+	groupMap := make(map[string]int)
+	for i, rec := range shiftRecords(gffRecords, opts.Offset) {
+		if !opts.selects(gffRecords[i]) {
+			continue
+		}
+		id := gffAttribute(rec, "ID")
+		if k, found := groupMap[id]; found && id != "" {
+			groups[k] = append(groups[k], rec)
 			continue
 		}
+		if id != "" {
+			groupMap[id] = len(groups)
+		}
+		groups = append(groups, []*gff.Record{rec})
+		geneIndices = append(geneIndices, i+1)
+	}
+
+	names := make(map[string]bool)
+	for k, segments := range groups {
+		segments = orderSegments(segments, len(genome), opts.Circular)
+		var indices []int
+		for _, rec := range segments {
+			segment := codingIndices(rec.Start, rec.End, len(genome), opts.Circular)
+			if segment == nil {
+				// skip genes across boundary.
+				indices = nil
+				break
+			}
+			indices = append(indices, segment...)
+		}
+		if indices == nil {
+			if opts.Stats != nil {
+				opts.Stats.SkippedGenes++
+			}
+			continue
+		}
+
+		// the first segment in the transcription direction.
+		first := segments[0]
+		reverse := first.Strand == gff.ReverseStrand
+		if reverse {
+			first = segments[len(segments)-1]
+		}
+		// a stable name from the GFF attributes,
+		// or from the record index if there is none.
+		name := gffAttribute(first, "locus_tag")
+		if name == "" {
+			name = gffAttribute(first, "ID")
+		}
+		if name == "" || names[name] {
+			name = fmt.Sprintf("%s_%d", first.SeqName, geneIndices[k])
+		}
+		names[name] = true
 
-		// reverse and complement the negative strain.
+		genes = append(genes, cds{
+			name:    name,
+			indices: indices,
+			reverse: reverse,
+			// the GFF phase is the number of bases to remove
+			// from the start of the CDS to reach the first codon.
+			phase: int(first.Frame),
+			rec:   first,
+		})
+	}
+	return
+}
+
+// selects returns true if the GFF record is selected
+// by the GeneFilter and the Strand of the options.
+func (opts Options) selects(rec *gff.Record) bool {
+	switch opts.Strand {
+	case '+':
 		if rec.Strand == gff.ReverseStrand {
-			nucl = seq.Complement(seq.Reverse(nucl))
+			return false
+		}
+	case '-':
+		if rec.Strand != gff.ReverseStrand {
+			return false
 		}
+	}
+	return opts.GeneFilter == nil || opts.GeneFilter(rec)
+}
 
-		prof := make([]byte, len(nucl))
-		for j, _ := range nucl {
-			switch (j + 1) % 3 {
-			case 1:
-				prof[j] = FirstPos
-			case 2:
-				prof[j] = SecondPos
-			case 0:
-				// determine if it is a fourfold site.
-				codon := nucl[j-2 : j+1]
-				if gc.FFCodons[string(codon)] {
-					prof[j] = FourFold
-				} else {
-					prof[j] = ThirdPos
-				}
+// filterRecords returns the records selected by the filter,
+// or all records if the filter is nil.
+func filterRecords(gffRecords []*gff.Record, filter func(*gff.Record) bool) []*gff.Record {
+	if filter == nil {
+		return gffRecords
+	}
+
+	var selected []*gff.Record
+	for _, rec := range gffRecords {
+		if filter(rec) {
+			selected = append(selected, rec)
+		}
+	}
+	return selected
+}
+
+// shiftRecords returns the records with the offset subtracted from their coordinates,
+// leaving the records unchanged.
+func shiftRecords(gffRecords []*gff.Record, offset int) []*gff.Record {
+	if offset == 0 {
+		return gffRecords
+	}
+
+	shifted := make([]*gff.Record, len(gffRecords))
+	for i, rec := range gffRecords {
+		r := *rec
+		r.Start -= offset
+		r.End -= offset
+		shifted[i] = &r
+	}
+	return shifted
+}
+
+// ValidateAnnotation returns an error for every GFF record
+// whose coordinates fall outside [1, genomeLen],
+// e.g. when the annotation is of another assembly version than the genome.
+// Such records are skipped in profiling.
+func ValidateAnnotation(genomeLen int, gffRecords []*gff.Record) (errs []error) {
+	for i, rec := range gffRecords {
+		if rec.Start < 1 || rec.End < 1 || rec.Start > genomeLen || rec.End > genomeLen {
+			name := gffAttribute(rec, "ID")
+			if name == "" {
+				name = fmt.Sprintf("%s record %d", rec.SeqName, i+1)
 			}
+			errs = append(errs, fmt.Errorf("%s: coordinates %d..%d outside genome of length %d", name, rec.Start, rec.End, genomeLen))
 		}
+	}
+	return
+}
 
-		// if it is a negative strain, reverse the profile to match the positive strain.
-		if rec.Strand == gff.ReverseStrand {
-			prof = seq.Reverse(prof)
+// orderSegments sorts the segments of a CDS in the positive strand order.
+// On a circular genome, segments wrapping around the origin
+// start after the largest gap between segments.
+func orderSegments(segments []*gff.Record, genomeLen int, circular bool) []*gff.Record {
+	if len(segments) < 2 {
+		return segments
+	}
+	sorted := make([]*gff.Record, len(segments))
+	copy(sorted, segments)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	if circular && sorted[0].Start == 1 && sorted[len(sorted)-1].End == genomeLen {
+		start, gap := 0, 0
+		for i := 1; i < len(sorted); i++ {
+			if g := sorted[i].Start - sorted[i-1].End; g > gap {
+				start, gap = i, g
+			}
 		}
+		sorted = append(sorted[start:], sorted[:start]...)
+	}
+	return sorted
+}
 
-		// write the position profile into the entire genomic profile.
-		for j, p := range prof {
-			index := rec.Start - 1 + j
-			// check overlapping.
-			// if overlap, simply mark it as undefined.
-			base := genome[index]
-			if profile[index].Type == NonCoding {
-				profile[index] = Pos{Type: p, Base: base, Gene: fmt.Sprintf("%s_%d", rec.SeqName, geneIndex)}
-			} else {
-				profile[index] = Pos{Type: Undefined, Base: base, Gene: fmt.Sprintf("%s_%d", rec.SeqName, geneIndex)}
+// gffAttribute returns the value of the attribute of a GFF record,
+// e.g. "ID=cds-NP_414542.1;locus_tag=b0001" in GFF3,
+// or an empty string if the record does not have the attribute.
+// The percent-encoded characters of a GFF3 value, e.g. "%2C" for a comma, are decoded,
+// while a GTF value (key "value") is returned as is.
+func gffAttribute(rec *gff.Record, key string) string {
+	for _, attr := range strings.Split(rec.Attributes, ";") {
+		attr = strings.TrimSpace(attr)
+		if i := strings.IndexAny(attr, "= "); i > 0 && attr[:i] == key {
+			value := strings.Trim(strings.TrimSpace(attr[i+1:]), "\"")
+			if attr[i] == '=' {
+				if unescaped, err := url.PathUnescape(value); err == nil {
+					value = unescaped
+				}
 			}
+			return value
 		}
 	}
+	return ""
+}
 
-	return
+// IsSelenoprotein returns true if the GFF record has a selenocysteine translation exception,
+// e.g. "transl_except=(pos:1234..1236%2Caa:Sec)" in NCBI GFF3;
+// it may be used as Options.Selenoproteins.
+func IsSelenoprotein(rec *gff.Record) bool {
+	return strings.Contains(gffAttribute(rec, "transl_except"), "aa:Sec")
 }
 
 // Generate codon position profile for the entire genome.
 // First, we mark every position as NonCoding.
 // Then, for each coding (gene) region, we determine each codon position.
 // If there is an overlapping region between two genes, mark them as undefined.
+//
+// ProfileGenome1 panics if the genome or the ptt file can not be read;
+// use ProfileGenomeFromFiles to get the error instead.
 func ProfileGenome1(genomeFileName, pttFileName string, gc *taxonomy.GeneticCode) (profile []Pos) {
+	profile, err := ProfileGenomeFromFiles(genomeFileName, pttFileName, gc)
+	if err != nil {
+		panic(err)
+	}
+
+	return
+}
+
+// ProfileGenomeFromFiles generates codon position profile for the entire genome,
+// reading the genome sequence from a FASTA file and the gene coding regions from a .ptt file,
+// either of which may be gzip-compressed.
+func ProfileGenomeFromFiles(genomeFileName, pttFileName string, gc *taxonomy.GeneticCode) (profile []Pos, err error) {
+	return profileFilesContext(context.Background(), genomeFileName, pttFileName, gc)
+}
+
+// profileFilesContext is like ProfileGenomeFromFiles,
+// but returns early with the context error if the context is done,
+// which is checked after reading each file and while profiling.
+func profileFilesContext(ctx context.Context, genomeFileName, pttFileName string, gc *taxonomy.GeneticCode) (profile []Pos, err error) {
+	if err := checkGeneticCode(gc, Options{}); err != nil {
+		return nil, err
+	}
+
 	// read .ptt file and obtain gene coding region.
-	ptts := readPtt(pttFileName)
+	ptts, err := readPtt(pttFileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	// read genome sequence.
-	genome := readGenome(genomeFileName)
-	s := genome.Seq
+	genome, err := readGenome(genomeFileName)
+	if err != nil {
+		return nil, err
+	}
 
-	// mark all sites as non-coding.
-	profile = make([]Pos, len(s))
-	for i := 0; i < len(profile); i++ {
-		profile[i] = Pos{Type: NonCoding}
+	return profilePttsContext(ctx, genome.Seq, ptts, gc)
+}
+
+// ProfileGenomeFromReaders is like ProfileGenomeFromFiles,
+// but reads the FASTA genome and the .ptt records from readers,
+// e.g. from memory.
+func ProfileGenomeFromReaders(genome, ptt io.Reader, gc *taxonomy.GeneticCode) (profile []Pos, err error) {
+	if err := checkGeneticCode(gc, Options{}); err != nil {
+		return nil, err
+	}
+
+	ptts, err := seqrecord.NewPttReader(ptt).ReadPtts()
+	if err != nil {
+		return nil, err
+	}
+	sequences, err := readFasta(genome, "genome")
+	if err != nil {
+		return nil, err
+	}
+
+	return ProfilePtts(sequences[0].Seq, ptts, gc), nil
+}
+
+// ProfileGenomeGFF generates codon position profile for the entire genome,
+// reading the genome sequence from a FASTA file and the CDS features from a GFF file,
+// either of which may be gzip-compressed.
+// The genome is the first sequence of the FASTA file;
+// use ProfileMultiGenome for a genome of several replicons.
+func ProfileGenomeGFF(genomeFileName, gffFileName string, gc *taxonomy.GeneticCode) (profile []Pos, err error) {
+	if err := checkGeneticCode(gc, Options{}); err != nil {
+		return nil, err
+	}
+
+	records, err := readGff(gffFileName)
+	if err != nil {
+		return nil, err
+	}
+	genome, err := readGenome(genomeFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return ProfileGenome(genome.Seq, records, gc), nil
+}
+
+// ProfileGenomeWithRNA is like ProfileGenomeFromFiles,
+// but also marks the non-coding sites of the RNA genes of a .rnt file as RNA,
+// so that they are distinct from the intergenic sites.
+// Sites shared by an RNA gene and a CDS keep the type of the CDS.
+func ProfileGenomeWithRNA(genomeFileName, pttFileName, rntFileName string, gc *taxonomy.GeneticCode) (profile []Pos, err error) {
+	profile, err = ProfileGenomeFromFiles(genomeFileName, pttFileName, gc)
+	if err != nil {
+		return nil, err
+	}
+	rnts, err := readPtt(rntFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	markRNA(profile, rnts)
+	return
+}
+
+// markRNA marks the non-coding sites of the RNA genes as RNA.
+func markRNA(profile []Pos, rnts []seqrecord.Ptt) {
+	for _, rnt := range rnts {
+		name := rnt.PID
+		if name == "" || name == "-" {
+			name = rnt.SynonymCode
+		}
+		for _, index := range pttIndices(rnt, len(profile)) {
+			if profile[index].Type == NonCoding {
+				profile[index].Type = RNA
+				profile[index].Gene = name
+				if rnt.Loc.Strand == "+" || rnt.Loc.Strand == "-" {
+					profile[index].Strand = rnt.Loc.Strand[0]
+				}
+			}
+		}
+	}
+}
+
+// ProfileGenomeGenBank generates codon position profiles
+// for each replicon of a GenBank flat file (.gbk or .gbff),
+// which contains both the sequences and the CDS features.
+// The profiles are keyed by the accession with version.
+func ProfileGenomeGenBank(fileName string, gc *taxonomy.GeneticCode) (profiles map[string][]Pos, err error) {
+	if err := checkGeneticCode(gc, Options{}); err != nil {
+		return nil, err
 	}
 
+	f, err := seqrecord.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := seqrecord.ReadGenBank(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	profiles = make(map[string][]Pos)
+	for _, rec := range records {
+		profiles[rec.Id] = ProfilePtts(rec.Seq, rec.CDS, gc)
+	}
+
+	return
+}
+
+// ProfilePtts generates codon position profile for the genome,
+// from the gene coding regions of ptt records.
+// It panics if the genetic code is nil or has no fourfold codons.
+func ProfilePtts(s []byte, ptts []seqrecord.Ptt, gc *taxonomy.GeneticCode) (profile []Pos) {
+	mustCheckGeneticCode(gc, Options{})
+	// the background context is never done.
+	profile, _ = profilePttsContext(context.Background(), s, ptts, gc)
+	return
+}
+
+// profilePttsContext is like ProfilePtts,
+// but returns early with the context error if the context is done.
+// The context is checked every contextCheckGenes genes.
+func profilePttsContext(ctx context.Context, s []byte, ptts []seqrecord.Ptt, gc *taxonomy.GeneticCode) (profile []Pos, err error) {
+	// mark all sites as non-coding.
+	w := newGeneWriter(s, Options{})
+
 	// for each gene, mark codon positions.
-	for _, ptt := range ptts {
-		// prepare nucleotide sequence,
-		// we need it for determine 4-fold codons.
-		var nucl []byte
-		if ptt.Loc.To >= ptt.Loc.From {
-			nucl = s[ptt.Loc.From-1 : ptt.Loc.To]
-		} else {
+	for i, ptt := range ptts {
+		if i%contextCheckGenes == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		indices := pttIndices(ptt, len(s))
+		if indices == nil {
 			// skip genes across boundary.
 			continue
 		}
 
-		// reverse and complement the negative strain.
-		if ptt.Loc.Strand == "-" {
-			nucl = seq.Complement(seq.Reverse(nucl))
+		// CDS features of GenBank files may not have a protein id.
+		name := ptt.PID
+		if name == "" {
+			name = ptt.SynonymCode
 		}
+		g := cds{name: name, indices: indices, reverse: ptt.Loc.Strand == "-"}
+		w.write(profileGene(s, g, gc, Options{}))
+	}
 
-		prof := make([]byte, len(nucl))
-		for j, _ := range nucl {
-			switch (j + 1) % 3 {
-			case 1:
-				prof[j] = FirstPos
-			case 2:
-				prof[j] = SecondPos
-			case 0:
-				// determine if it is a fourfold site.
-				codon := nucl[j-2 : j+1]
-				if gc.FFCodons[string(codon)] {
-					prof[j] = FourFold
-				} else {
-					prof[j] = ThirdPos
-				}
+	return w.profile, nil
+}
+
+// ProfileMultiGenome generates codon position profiles for a genome
+// containing more than one replicon (chromosomes, plasmids or contigs),
+// reading the sequences from a FASTA file and the CDS features from a GFF file.
+// The profiles are keyed by the FASTA sequence ID,
+// and each GFF record is assigned to the replicon matching its SeqName.
+// A .ptt file can not be used instead of the GFF file:
+// it has no SeqName column, so its records can not be assigned to replicons;
+// use ProfileStrainPtt with the .ptt file of each replicon.
+func ProfileMultiGenome(genomeFileName, gffFileName string, gc *taxonomy.GeneticCode) (profiles map[string][]Pos, err error) {
+	if err := checkGeneticCode(gc, Options{}); err != nil {
+		return nil, err
+	}
+
+	records, err := readGff(gffFileName)
+	if err != nil {
+		return nil, err
+	}
+	replicons, err := readGenomes(genomeFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return ProfileReplicons(replicons, records, gc, Options{}), nil
+}
+
+// ProfileReplicons generates codon position profiles for each replicon,
+// keyed by the sequence ID. A GFF record belongs to the replicon
+// whose ID matches its SeqName, ignoring the accession version;
+// records matching no replicon are skipped.
+func ProfileReplicons(replicons []*seq.Sequence, gffRecords []*gff.Record, gc *taxonomy.GeneticCode, opts Options) (profiles map[string][]Pos) {
+	// group records by replicon.
+	idMap := make(map[string]string)
+	for _, r := range replicons {
+		idMap[r.Id] = r.Id
+		idMap[accession(r.Id)] = r.Id
+	}
+	recMap := make(map[string][]*gff.Record)
+	for _, rec := range gffRecords {
+		id, found := idMap[rec.SeqName]
+		if !found {
+			id, found = idMap[accession(rec.SeqName)]
+		}
+		if found {
+			recMap[id] = append(recMap[id], rec)
+		}
+	}
+
+	profiles = make(map[string][]Pos)
+	for _, r := range replicons {
+		profiles[r.Id] = ProfileGenomeWithOptions(r.Seq, recMap[r.Id], gc, opts)
+	}
+
+	return
+}
+
+// SplitByReplicon splits a profile of concatenated replicons into the profile of each replicon,
+// keyed by its name. The boundaries are the start indices (0-based) of the replicons after the first,
+// in increasing order, so that there is one more name than boundaries;
+// a final boundary at the end of the profile may be given, with as many names as boundaries.
+// The profiles of the replicons are slices of the profile.
+// It returns an error if the boundaries are not increasing, are out of the profile,
+// or do not match the names.
+func SplitByReplicon(profile []Pos, boundaries []int, names []string) (profiles map[string][]Pos, err error) {
+	if n := len(boundaries); n > 0 && boundaries[n-1] == len(profile) {
+		boundaries = boundaries[:n-1]
+	}
+	if len(names) != len(boundaries)+1 {
+		return nil, fmt.Errorf("profiling: %d replicon names for %d boundaries", len(names), len(boundaries))
+	}
+
+	profiles = make(map[string][]Pos)
+	start := 0
+	for i, name := range names {
+		end := len(profile)
+		if i < len(boundaries) {
+			end = boundaries[i]
+		}
+		if end <= start || end > len(profile) {
+			return nil, fmt.Errorf("profiling: invalid boundary %d of replicon %s", end, name)
+		}
+		if _, found := profiles[name]; found {
+			return nil, fmt.Errorf("profiling: duplicate replicon %s", name)
+		}
+		profiles[name] = profile[start:end:end]
+		start = end
+	}
+
+	return
+}
+
+// accession returns the sequence accession without its version.
+func accession(id string) string {
+	fields := strings.Fields(id)
+	if len(fields) == 0 {
+		return id
+	}
+	return strings.Split(fields[0], ".")[0]
+}
+
+// pttIndices returns the genome indices of a ptt record,
+// assembling the coding sequence from the segments of a joined location.
+func pttIndices(ptt seqrecord.Ptt, genomeLen int) (indices []int) {
+	if len(ptt.Segments) == 0 {
+		return codingIndices(ptt.Loc.From, ptt.Loc.To, genomeLen, false)
+	}
+
+	for _, loc := range ptt.Segments {
+		segment := codingIndices(loc.From, loc.To, genomeLen, false)
+		if segment == nil {
+			return nil
+		}
+		indices = append(indices, segment...)
+	}
+	return
+}
+
+// codingIndices returns the genome indices (0-based) of a gene
+// from start to end (1-based, inclusive), in the positive strand order.
+// If end is before start, the gene wraps around the origin of a circular genome,
+// otherwise it returns nil.
+// It also returns nil if start or end is outside [1, genomeLen],
+// e.g. when the annotation is of another assembly version.
+func codingIndices(start, end, genomeLen int, circular bool) (indices []int) {
+	if start < 1 || end < 1 || start > genomeLen || end > genomeLen {
+		return nil
+	}
+	if end >= start {
+		for i := start - 1; i < end; i++ {
+			indices = append(indices, i)
+		}
+	} else if circular {
+		for i := start - 1; i < genomeLen; i++ {
+			indices = append(indices, i)
+		}
+		for i := 0; i < end; i++ {
+			indices = append(indices, i)
+		}
+	}
+
+	return
+}
+
+// cds is a coding region of a gene to be profiled.
+type cds struct {
+	name    string
+	indices []int // genome indices in the positive strand order.
+	reverse bool  // true if the gene is on the negative strand.
+	phase   int   // number of bases before the first complete codon.
+
+	rec *gff.Record // first GFF record in the transcription direction, nil for ptt records.
+}
+
+// sequence returns the nucleotide sequence of the gene in the transcription direction,
+// upper-cased, and the number of bases before its first complete codon.
+func (g cds) sequence(genome []byte) (nucl []byte, phase int) {
+	nucl = make([]byte, len(g.indices))
+	for j, index := range g.indices {
+		nucl[j] = genome[index]
+	}
+	// upper-case soft-masked (lowercase) bases,
+	// since the codon tables use uppercase codons.
+	nucl = bytes.ToUpper(nucl)
+
+	// reverse and complement the negative strain.
+	if g.reverse {
+		nucl = seq.Complement(seq.Reverse(nucl))
+	}
+
+	phase = g.phase
+	if phase < 0 || phase > 2 {
+		phase = 0
+	}
+	if phase > len(nucl) {
+		phase = len(nucl)
+	}
+	return
+}
+
+// strand returns the strand of the gene, '+' or '-'.
+func (g cds) strand() byte {
+	if g.reverse {
+		return '-'
+	}
+	return '+'
+}
+
+// geneProfile is the position profile of a gene,
+// in the same order as its genome indices.
+type geneProfile struct {
+	cds
+	prof    []byte // position types.
+	aas     []byte // amino acids.
+	partial bool   // true if the gene ends with a partial codon.
+}
+
+// profileGene determines codon positions of a gene.
+func profileGene(genome []byte, g cds, gc *taxonomy.GeneticCode, opts Options) geneProfile {
+	// prepare nucleotide sequence,
+	// we need it for determine 4-fold codons.
+	nucl, phase := g.sequence(genome)
+
+	// the bases before the first complete codon belong to
+	// a codon we do not see, so mark them as undefined.
+	prof := make([]byte, len(nucl))
+	aas := make([]byte, len(nucl))
+	for j := 0; j < phase; j++ {
+		prof[j] = Undefined
+	}
+	sec := opts.Selenoproteins != nil && g.rec != nil && opts.Selenoproteins(g.rec)
+	partial := profileCodons(prof[phase:], aas[phase:], nucl[phase:], gc, opts, sec)
+
+	// a gene without phase begins with its start codon.
+	if opts.StartCodons && phase == 0 && len(nucl) >= 3 && gc.IsStartCodon(string(nucl[:3])) {
+		prof[0], prof[1], prof[2] = StartCodon, StartCodon, StartCodon
+		aas[0], aas[1], aas[2] = 'M', 'M', 'M'
+	}
+
+	// if it is a negative strain, reverse the profile to match the positive strain.
+	if g.reverse {
+		prof = seq.Reverse(prof)
+		aas = seq.Reverse(aas)
+	}
+
+	return geneProfile{cds: g, prof: prof, aas: aas, partial: partial}
+}
+
+// geneWriter writes the position profiles of genes into the entire genomic profile.
+type geneWriter struct {
+	profile []Pos     // the written profile, nil if the sites are not stored as a []Pos.
+	sites   siteStore // where the sites are written.
+	genome  []byte
+	opts    Options
+	reverse map[string]bool // strand of each written gene.
+	lengths map[string]int  // number of sites of each written gene.
+}
+
+// siteStore stores the sites of a profile written by a geneWriter,
+// e.g. a []Pos or an IndexedProfile.
+type siteStore interface {
+	site(i int) Pos
+	setSite(i int, p Pos)
+}
+
+// posStore stores the sites in a []Pos profile.
+type posStore []Pos
+
+func (s posStore) site(i int) Pos       { return s[i] }
+func (s posStore) setSite(i int, p Pos) { s[i] = p }
+
+// newGeneWriter returns a geneWriter of a profile with all sites marked as non-coding.
+func newGeneWriter(genome []byte, opts Options) *geneWriter {
+	profile := newProfile(len(genome))
+	w := newSiteWriter(genome, opts, posStore(profile))
+	w.profile = profile
+	return w
+}
+
+// newSiteWriter returns a geneWriter of the sites,
+// which must be all marked as non-coding.
+func newSiteWriter(genome []byte, opts Options, sites siteStore) *geneWriter {
+	return &geneWriter{
+		sites:   sites,
+		genome:  genome,
+		opts:    opts,
+		reverse: make(map[string]bool),
+		lengths: make(map[string]int),
+	}
+}
+
+// write writes the position profile of a gene into the entire genomic profile.
+func (w *geneWriter) write(gp geneProfile) {
+	if gp.partial && w.opts.Stats != nil {
+		w.opts.Stats.PartialGenes++
+	}
+	w.reverse[gp.name] = gp.reverse
+	w.lengths[gp.name] = len(gp.prof)
+
+	// write the position profile into the entire genomic profile.
+	strand := gp.strand()
+	repeats := repeatedIndices(gp.indices)
+	for j, p := range gp.prof {
+		index := gp.indices[j]
+		// a base read twice by the gene is not an overlap,
+		// it keeps the site of its first reading.
+		if written, repeated := repeats[index]; repeated {
+			if written {
+				continue
 			}
+			repeats[index] = true
+		}
+		// check overlapping.
+		// if overlap, simply mark it as undefined.
+		base := w.genome[index]
+		site := Pos{Type: p, Base: base, Gene: gp.name, AminoAcid: gp.aas[j], Strand: strand}
+		current := w.sites.site(index)
+		if current.Type == NonCoding {
+			w.sites.setSite(index, site)
+			continue
 		}
 
-		// if it is a negative strain, reverse the profile to match the positive strain.
-		if ptt.Loc.Strand == "-" {
-			prof = seq.Reverse(prof)
+		genes := current.Genes
+		if genes == nil {
+			genes = []string{current.Gene}
+		}
+		genes = append(genes, gp.name)
+		switch w.opts.Overlap {
+		case FirstWins:
+			current.Genes = genes
+			w.sites.setSite(index, current)
+			continue
+		case LongestWins:
+			if len(gp.prof) > w.lengths[current.Gene] {
+				current = site
+			}
+			current.Genes = genes
+			w.sites.setSite(index, current)
+			continue
+		}
+		t := Undefined
+		if w.opts.AntisenseOverlaps && w.antisense(genes[:len(genes)-1], gp.reverse) {
+			t = AntisenseOverlap
 		}
+		w.sites.setSite(index, Pos{Type: t, Base: base, Gene: gp.name, Genes: genes, Strand: strand})
+	}
+}
 
-		// write the position profile into the entire genomic profile.
-		for j, p := range prof {
-			index := ptt.Loc.From - 1 + j
-			// check overlapping.
-			// if overlap, simply mark it as undefined.
-			base := s[index]
-			if profile[index].Type == NonCoding {
-				profile[index] = Pos{Type: p, Base: base, Gene: ptt.PID}
+// repeatedIndices returns the indices appearing more than once in the indices of a gene,
+// e.g. the junction base of a -1 ribosomal frameshift, join(a..b,b..c),
+// mapped to false. It returns nil if the indices are increasing, as in most genes.
+func repeatedIndices(indices []int) map[int]bool {
+	increasing := true
+	for k := 1; k < len(indices); k++ {
+		if indices[k] <= indices[k-1] {
+			increasing = false
+			break
+		}
+	}
+	if increasing {
+		return nil
+	}
+
+	seen := make(map[int]bool)
+	var repeats map[int]bool
+	for _, i := range indices {
+		if seen[i] {
+			if repeats == nil {
+				repeats = make(map[int]bool)
+			}
+			repeats[i] = false
+		}
+		seen[i] = true
+	}
+	return repeats
+}
+
+// antisense returns true if any of the genes is on the other strand.
+func (w *geneWriter) antisense(genes []string, reverse bool) bool {
+	for _, g := range genes {
+		if w.reverse[g] != reverse {
+			return true
+		}
+	}
+	return false
+}
+
+// profileCodons determines the position type and the amino acid of each site
+// of an in-frame coding sequence, in the transcription direction.
+// If sec is true, the internal TGA codons are translated as selenocysteine.
+// It returns true if the sequence ends with a partial codon.
+func profileCodons(prof, aas, nucl []byte, gc *taxonomy.GeneticCode, opts Options, sec bool) (partial bool) {
+	ffCodons := gc.FFCodons
+	if opts.FFCodons != nil {
+		ffCodons = opts.FFCodons
+	}
+
+	for j, _ := range nucl {
+		switch (j + 1) % 3 {
+		case 1:
+			prof[j] = FirstPos
+		case 2:
+			prof[j] = SecondPos
+		case 0:
+			// determine if it is a fourfold site.
+			codon := nucl[j-2 : j+1]
+			if ffCodons[string(codon)] {
+				prof[j] = FourFold
 			} else {
-				profile[index] = Pos{Type: Undefined, Base: base, Gene: ptt.PID}
+				prof[j] = ThirdPos
+			}
+		}
+	}
+
+	if opts.Degeneracy {
+		markDegeneracy(prof, nucl, gc)
+	}
+
+	// the final codon is partial if the length is not a multiple of three,
+	// and we can not determine its positions.
+	if n := len(nucl) % 3; n != 0 {
+		partial = true
+		for j := len(nucl) - n; j < len(nucl); j++ {
+			prof[j] = Undefined
+		}
+	}
+
+	// a codon containing an ambiguous base (N, R, Y, etc.)
+	// can not be classified, so mark all of its positions as undefined.
+	for j := 0; j+3 <= len(nucl); j += 3 {
+		if !isUnambiguous(nucl[j : j+3]) {
+			prof[j], prof[j+1], prof[j+2] = Undefined, Undefined, Undefined
+		}
+	}
+
+	// translate each codon, and assign the amino acid to its sites.
+	for j := 0; j+3 <= len(nucl); j += 3 {
+		aa := gc.Table[string(nucl[j:j+3])]
+		aas[j], aas[j+1], aas[j+2] = aa, aa, aa
+	}
+
+	// in a selenoprotein, an internal TGA is read through as selenocysteine,
+	// whose codon has no synonymous substitution.
+	if sec {
+		for j := 0; j+6 <= len(nucl); j += 3 {
+			codon := string(nucl[j : j+3])
+			if !strings.EqualFold(codon, "TGA") || !gc.IsStopCodon(codon) {
+				continue
+			}
+			aas[j], aas[j+1], aas[j+2] = 'U', 'U', 'U'
+			if opts.Degeneracy {
+				prof[j], prof[j+1], prof[j+2] = ZeroFold, ZeroFold, ZeroFold
 			}
 		}
 	}
 
+	// the terminal stop codon is not coding in the synonymous sense.
+	// a truncated gene, ending with a partial codon or a sense codon,
+	// has no stop codon to mark.
+	if n := len(nucl); !partial && n >= 3 && gc.IsStopCodon(string(nucl[n-3:])) {
+		prof[n-3], prof[n-2], prof[n-1] = StopCodon, StopCodon, StopCodon
+	}
+
 	return
 }
 
+// isUnambiguous returns true if the sequence contains only A, C, G and T.
+func isUnambiguous(nucl []byte) bool {
+	for _, b := range nucl {
+		switch b {
+		case 'A', 'C', 'G', 'T', 'a', 'c', 'g', 't':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// markDegeneracy classifies each site of complete codons
+// by the number of synonymous substitutions.
+func markDegeneracy(prof, nucl []byte, gc *taxonomy.GeneticCode) {
+	for j := 0; j+3 <= len(nucl); j += 3 {
+		codon := string(nucl[j : j+3])
+		for k := 0; k < 3; k++ {
+			switch gc.Degeneracy(codon, k) {
+			case 0:
+				prof[j+k] = ZeroFold
+			case 1:
+				prof[j+k] = TwoFold
+			case 2:
+				prof[j+k] = ThreeFold
+			case 3:
+				prof[j+k] = FourFold
+			}
+		}
+	}
+}
+
 // read ptt file.
-func readPtt(fileName string) []seqrecord.Ptt {
-	reader := seqrecord.NewPttFile(fileName)
-	ptts := reader.ReadAll()
-	return ptts
+func readPtt(fileName string) ([]seqrecord.Ptt, error) {
+	reader, err := seqrecord.OpenPttFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	ptts, err := reader.ReadPtts()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return ptts, nil
+}
+
+// read CDS features from a GFF file, which may be gzip-compressed.
+func readGff(fileName string) ([]*gff.Record, error) {
+	f, err := seqrecord.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := gff.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	var cds []*gff.Record
+	for _, rec := range records {
+		if rec.Feature == "CDS" {
+			cds = append(cds, rec)
+		}
+	}
+	return cds, nil
 }
 
 // read genome sequence from a FASTA file.
-func readGenome(fileName string) *seq.Sequence {
-	f, err := os.Open(fileName)
+func readGenome(fileName string) (*seq.Sequence, error) {
+	sequences, err := readGenomes(fileName)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	return sequences[0], nil
+}
+
+// read all replicon sequences from a FASTA file, which may be gzip-compressed.
+func readGenomes(fileName string) ([]*seq.Sequence, error) {
+	f, err := seqrecord.Open(fileName)
+	if err != nil {
+		return nil, err
 	}
 	defer f.Close()
 
-	reader := seq.NewFastaReader(f)
+	return readFasta(f, fileName)
+}
+
+// read all sequences of a FASTA file from a reader,
+// the name is used in errors.
+func readFasta(r io.Reader, name string) ([]*seq.Sequence, error) {
+	reader := seq.NewFastaReader(r)
 	sequences, err := reader.ReadAll()
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if len(sequences) == 0 {
+		return nil, fmt.Errorf("%s: no sequence found", name)
 	}
 
-	return sequences[0]
+	return sequences, nil
 }
@@ -0,0 +1,156 @@
+package profiling
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kussell-lab/biogo/feat/gff"
+	"github.com/kussell-lab/biogo/seq"
+	"github.com/kussell-lab/ncbiftp/taxonomy"
+)
+
+// standardCode returns the bacterial genetic code.
+func standardCode(t *testing.T) *taxonomy.GeneticCode {
+	gc, found := taxonomy.GeneticCodes()["11"]
+	if !found {
+		t.Fatal("genetic code 11 not found")
+	}
+	return gc
+}
+
+// cdsRecord returns a GFF CDS record of the gene with a 1-based location.
+func cdsRecord(id string, start, end int, reverse bool, phase int) *gff.Record {
+	rec := &gff.Record{SeqName: "chr", Feature: "CDS", Start: start, End: end, Attributes: "ID=" + id}
+	if reverse {
+		rec.Strand = gff.ReverseStrand
+	}
+	// constants, so as not to depend on the integer type of Frame.
+	switch phase {
+	case 1:
+		rec.Frame = 1
+	case 2:
+		rec.Frame = 2
+	}
+	return rec
+}
+
+// profileTypes returns the position types of the profile as a string.
+func profileTypes(profile []Pos) string {
+	types := make([]byte, len(profile))
+	for i, p := range profile {
+		types[i] = p.Type
+	}
+	return string(types)
+}
+
+func TestAmbiguousCodonsUndefined(t *testing.T) {
+	// ATG GCN CTN TAA: GCN and CTN are fourfold codons but for the N.
+	genome := []byte("ATGGCNCTNTAA")
+	recs := []*gff.Record{cdsRecord("g", 1, len(genome), false, 0)}
+	profile := ProfileGenome(genome, recs, standardCode(t))
+
+	if got, want := profileTypes(profile), "123555555EEE"; got != want {
+		t.Errorf("types = %s, want %s", got, want)
+	}
+	for _, i := range []int{5, 8} {
+		if profile[i].Type == ThirdPos || profile[i].Type == FourFold {
+			t.Errorf("site %d of an ambiguous codon is %s", i, ProfileType(profile[i].Type))
+		}
+	}
+}
+
+func TestSoftMaskedCodons(t *testing.T) {
+	// the lowercase gcc is profiled as the uppercase GCC.
+	genome := []byte("ATGgccGCCTAA")
+	recs := []*gff.Record{cdsRecord("g", 1, len(genome), false, 0)}
+	profile := ProfileGenome(genome, recs, standardCode(t))
+
+	if got, want := profileTypes(profile), "123124124EEE"; got != want {
+		t.Errorf("types = %s, want %s", got, want)
+	}
+	for i := range genome {
+		if profile[i].Base != genome[i] {
+			t.Errorf("base %d = %c, want %c", i, profile[i].Base, genome[i])
+		}
+	}
+}
+
+func TestPhase(t *testing.T) {
+	gc := standardCode(t)
+	for _, phase := range []int{1, 2} {
+		// the phase bases precede the first codon in the transcription direction.
+		transcript := strings.Repeat("A", phase) + "ATGGCCTAA"
+		want := strings.Repeat(string(Undefined), phase) + "123124EEE"
+
+		genome := []byte(transcript)
+		recs := []*gff.Record{cdsRecord("g", 1, len(genome), false, phase)}
+		if got := profileTypes(ProfileGenome(genome, recs, gc)); got != want {
+			t.Errorf("phase %d, + strand: types = %s, want %s", phase, got, want)
+		}
+
+		genome = seq.Complement(seq.Reverse([]byte(transcript)))
+		reversed := string(seq.Reverse([]byte(want)))
+		recs = []*gff.Record{cdsRecord("g", 1, len(genome), true, phase)}
+		if got := profileTypes(ProfileGenome(genome, recs, gc)); got != reversed {
+			t.Errorf("phase %d, - strand: types = %s, want %s", phase, got, reversed)
+		}
+
+		// a joined negative strand CDS starts with its last segment,
+		// whose phase is used.
+		recs = []*gff.Record{
+			cdsRecord("g", 1, 5, true, 3-phase),
+			cdsRecord("g", 6, len(genome), true, phase),
+		}
+		if got := profileTypes(ProfileGenome(genome, recs, gc)); got != reversed {
+			t.Errorf("phase %d, joined - strand: types = %s, want %s", phase, got, reversed)
+		}
+	}
+}
+
+func TestFrameshiftJunction(t *testing.T) {
+	// a -1 frameshift reads the junction base (index 5) twice:
+	// ATG GCC | CGC TAA, joined as 1..6 and 6..12.
+	genome := []byte("ATGGCCGCTAA")
+	recs := []*gff.Record{
+		cdsRecord("g", 1, 6, false, 0),
+		cdsRecord("g", 6, len(genome), false, 0),
+	}
+	profile := ProfileGenome(genome, recs, standardCode(t))
+
+	if got, want := profileTypes(profile), "12312424EEE"; got != want {
+		t.Errorf("types = %s, want %s", got, want)
+	}
+	if genes := profile[5].Genes; genes != nil {
+		t.Errorf("junction genes = %v, want nil", genes)
+	}
+	if s := GeneStats(profile)["g"]; s.Overlapping || s.Length != len(genome) {
+		t.Errorf("gene stats = %+v, want %d non-overlapping sites", s, len(genome))
+	}
+}
+
+func TestGeneProtein(t *testing.T) {
+	gc := standardCode(t)
+	frameshift := []byte("ATGGCCGCTAA")
+	phased := []byte("AATGGCCTAA")
+	reversed := seq.Complement(seq.Reverse(phased))
+	tests := []struct {
+		name   string
+		genome []byte
+		recs   []*gff.Record
+		want   string
+	}{
+		{"phase 0", []byte("ATGGCCTAA"), []*gff.Record{cdsRecord("g", 1, 9, false, 0)}, "MA*"},
+		{"phase 1", phased, []*gff.Record{cdsRecord("g", 1, len(phased), false, 1)}, "MA*"},
+		{"phase 1, - strand", reversed, []*gff.Record{cdsRecord("g", 1, len(reversed), true, 1)}, "MA*"},
+		{"frameshift", frameshift, []*gff.Record{
+			cdsRecord("g", 1, 6, false, 0),
+			cdsRecord("g", 6, len(frameshift), false, 0),
+		}, "MAR*"},
+	}
+	for _, test := range tests {
+		profile := ProfileGenome(test.genome, test.recs, gc)
+		if got := string(GeneProtein(profile)["g"]); got != test.want {
+			t.Errorf("%s: protein = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
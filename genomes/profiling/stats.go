@@ -0,0 +1,248 @@
+package profiling
+
+import "math"
+
+// SummarizeProfile returns the number of positions of each position type
+// (NonCoding, FirstPos, SecondPos, ThirdPos, FourFold, Undefined, ...).
+func SummarizeProfile(profile []Pos) map[byte]int {
+	counts := make(map[byte]int)
+	for _, p := range profile {
+		counts[p.Type]++
+	}
+	return counts
+}
+
+// GC4 returns the GC content at fourfold-degenerate sites,
+// ignoring ambiguous bases.
+// It returns NaN if there is no unambiguous fourfold site.
+func GC4(profile []Pos) float64 {
+	var gc, total int
+	for _, p := range profile {
+		if p.Type != FourFold {
+			continue
+		}
+		switch p.Base {
+		case 'G', 'C', 'g', 'c':
+			gc++
+			total++
+		case 'A', 'T', 'a', 't':
+			total++
+		}
+	}
+
+	if total == 0 {
+		return math.NaN()
+	}
+	return float64(gc) / float64(total)
+}
+
+// FourFoldSites returns the bases at the fourfold-degenerate sites of the profile,
+// concatenated in the genome order.
+func FourFoldSites(profile []Pos) (bases []byte) {
+	for _, p := range profile {
+		if p.Type == FourFold {
+			bases = append(bases, p.Base)
+		}
+	}
+	return
+}
+
+// FourFoldSitesByGene returns the bases at the fourfold-degenerate sites of each gene,
+// concatenated in the order of the gene profile (see GeneProfile).
+func FourFoldSitesByGene(profile []Pos) (genes map[string][]byte) {
+	genes = make(map[string][]byte)
+	for name, indices := range geneIndices(profile) {
+		if hasFourFold(profile, name, indices) {
+			genes[name] = FourFoldSites(genePositions(profile, indices))
+		}
+	}
+	return
+}
+
+// hasFourFold returns true if the gene is assigned a FourFold position.
+func hasFourFold(profile []Pos, name string, indices []int) bool {
+	for _, i := range indices {
+		if p := profile[i]; p.Gene == name && p.Type == FourFold {
+			return true
+		}
+	}
+	return false
+}
+
+// CodingDensity returns the fraction of the positions of the profile that are coding,
+// i.e. whose type is not NonCoding or RNA, including Undefined overlapping positions.
+// It returns NaN for an empty profile.
+func CodingDensity(profile []Pos) float64 {
+	return codingDensity(profile, false)
+}
+
+// DefinedCodingDensity is like CodingDensity,
+// but does not count Undefined positions as coding.
+func DefinedCodingDensity(profile []Pos) float64 {
+	return codingDensity(profile, true)
+}
+
+// WindowedCodingDensity returns the CodingDensity of each window of windowSize positions,
+// the windows starting every step positions from the first position.
+// A final window shorter than windowSize is not included.
+// It returns nil if windowSize or step is not positive.
+func WindowedCodingDensity(profile []Pos, windowSize, step int) (densities []float64) {
+	if windowSize <= 0 || step <= 0 {
+		return nil
+	}
+
+	for start := 0; start+windowSize <= len(profile); start += step {
+		densities = append(densities, CodingDensity(profile[start:start+windowSize]))
+	}
+	return
+}
+
+// FourFoldGCSkew returns the GC skew (G-C)/(G+C) at the fourfold-degenerate sites
+// of each window of windowSize positions, the windows tiling the profile
+// from the first position, e.g. to locate the replication origin and terminus.
+// The bases are of the positive strand. A final window shorter than windowSize
+// is not included, and the skew of a window without G or C fourfold site is NaN.
+// It returns nil if windowSize is not positive.
+func FourFoldGCSkew(profile []Pos, windowSize int) (skews []float64) {
+	if windowSize <= 0 {
+		return nil
+	}
+
+	for start := 0; start+windowSize <= len(profile); start += windowSize {
+		var g, c int
+		for _, p := range profile[start : start+windowSize] {
+			if p.Type != FourFold {
+				continue
+			}
+			switch p.Base {
+			case 'G', 'g':
+				g++
+			case 'C', 'c':
+				c++
+			}
+		}
+
+		skew := math.NaN()
+		if g+c > 0 {
+			skew = float64(g-c) / float64(g+c)
+		}
+		skews = append(skews, skew)
+	}
+	return
+}
+
+func codingDensity(profile []Pos, skipUndefined bool) float64 {
+	if len(profile) == 0 {
+		return math.NaN()
+	}
+
+	coding := 0
+	for _, p := range profile {
+		if p.Type == NonCoding || p.Type == RNA || (skipUndefined && p.Type == Undefined) {
+			continue
+		}
+		coding++
+	}
+	return float64(coding) / float64(len(profile))
+}
+
+// GeneStat contains summary statistics of a gene in a profile.
+type GeneStat struct {
+	Length        int     // number of positions of the gene.
+	GC            float64 // GC content of the unambiguous bases, NaN if there is none.
+	FourFoldSites int     // number of fourfold-degenerate sites.
+	Overlapping   bool    // true if the gene overlaps another gene.
+}
+
+// GeneStats returns the statistics of each gene of the profile.
+// Overlapping positions count for every gene covering them.
+func GeneStats(profile []Pos) map[string]GeneStat {
+	stats := make(map[string]GeneStat)
+	gcs := make(map[string][2]int) // GC and unambiguous bases of each gene.
+	for _, p := range profile {
+		genes := p.Genes
+		if genes == nil {
+			if p.Gene == "" {
+				continue
+			}
+			genes = []string{p.Gene}
+		}
+
+		for _, g := range genes {
+			s := stats[g]
+			s.Length++
+			if p.Type == FourFold {
+				s.FourFoldSites++
+			}
+			if p.Genes != nil {
+				s.Overlapping = true
+			}
+			stats[g] = s
+
+			c := gcs[g]
+			switch p.Base {
+			case 'G', 'C', 'g', 'c':
+				c[0]++
+				c[1]++
+			case 'A', 'T', 'a', 't':
+				c[1]++
+			}
+			gcs[g] = c
+		}
+	}
+
+	for g, s := range stats {
+		s.GC = math.NaN()
+		if c := gcs[g]; c[1] > 0 {
+			s.GC = float64(c[0]) / float64(c[1])
+		}
+		stats[g] = s
+	}
+
+	return stats
+}
+
+// PositionComposition returns the counts of A, C, G and T (in this order)
+// at the FirstPos, SecondPos, ThirdPos and FourFold sites of the profile,
+// keyed by the site type. The bases of negative strand genes are complemented,
+// so that the counts are of the coding strand. Ambiguous bases are not counted.
+func PositionComposition(profile []Pos) map[byte][4]int {
+	comp := make(map[byte][4]int)
+	for _, t := range []byte{FirstPos, SecondPos, ThirdPos, FourFold} {
+		comp[t] = [4]int{}
+	}
+
+	for _, p := range profile {
+		counts, found := comp[p.Type]
+		if !found {
+			continue
+		}
+		k := baseIndex(p.Base)
+		if k < 0 {
+			continue
+		}
+		if p.Strand == '-' {
+			// A <-> T and C <-> G.
+			k = 3 - k
+		}
+		counts[k]++
+		comp[p.Type] = counts
+	}
+
+	return comp
+}
+
+// baseIndex returns the index of the base in ACGT, or -1 if it is ambiguous.
+func baseIndex(b byte) int {
+	switch b {
+	case 'A', 'a':
+		return 0
+	case 'C', 'c':
+		return 1
+	case 'G', 'g':
+		return 2
+	case 'T', 't':
+		return 3
+	}
+	return -1
+}
@@ -0,0 +1,59 @@
+package profiling
+
+import (
+	"fmt"
+
+	"github.com/kussell-lab/biogo/feat/gff"
+	"github.com/kussell-lab/biogo/seq"
+	"github.com/kussell-lab/ncbiftp/genomes/reports"
+	"github.com/kussell-lab/ncbiftp/seqrecord"
+	"github.com/kussell-lab/ncbiftp/taxonomy"
+)
+
+// ProfileStrain fills the Seq, Length and PosProfile of each genome of the strain,
+// from the replicon sequences whose ID matches its accession, ignoring the version,
+// and the CDS features of the GFF records.
+// It returns an error if the genetic code is invalid, or the sequence of a genome is missing.
+func ProfileStrain(s *reports.Strain, replicons []*seq.Sequence, gffRecords []*gff.Record, gc *taxonomy.GeneticCode, opts Options) error {
+	if err := checkGeneticCode(gc, opts); err != nil {
+		return err
+	}
+	profiles := ProfileReplicons(replicons, gffRecords, gc, opts)
+	return fillGenomes(s, replicons, func(r *seq.Sequence) []Pos {
+		return profiles[r.Id]
+	})
+}
+
+// ProfileStrainPtt is like ProfileStrain,
+// but reads the gene coding regions of each genome from ptt records,
+// keyed by the accession without version, as in the legacy .ptt files.
+func ProfileStrainPtt(s *reports.Strain, replicons []*seq.Sequence, ptts map[string][]seqrecord.Ptt, gc *taxonomy.GeneticCode) error {
+	if err := checkGeneticCode(gc, Options{}); err != nil {
+		return err
+	}
+	return fillGenomes(s, replicons, func(r *seq.Sequence) []Pos {
+		return ProfilePtts(r.Seq, ptts[accession(r.Id)], gc)
+	})
+}
+
+// fillGenomes fills each genome of the strain with its replicon sequence
+// and the position types of its profile.
+func fillGenomes(s *reports.Strain, replicons []*seq.Sequence, profile func(r *seq.Sequence) []Pos) error {
+	seqMap := make(map[string]*seq.Sequence)
+	for _, r := range replicons {
+		seqMap[accession(r.Id)] = r
+	}
+
+	for i := range s.Genomes {
+		g := &s.Genomes[i]
+		r, found := seqMap[accession(g.Accession)]
+		if !found {
+			return fmt.Errorf("%s: no sequence for %s", s.Name, g.Accession)
+		}
+		g.Seq = r.Seq
+		g.Length = len(r.Seq)
+		g.PosProfile = ProfileToBytes(profile(r))
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package profiling
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kussell-lab/biogo/feat/gff"
+	"github.com/kussell-lab/biogo/seq"
+	"github.com/kussell-lab/ncbiftp/taxonomy"
+)
+
+// Effect is the effect of a single nucleotide substitution on the encoded protein.
+type Effect int
+
+const (
+	OutsideCDS    Effect = iota // the site is not in a complete codon of a gene.
+	Synonymous                  // the amino acid is unchanged.
+	NonSynonymous               // the amino acid is changed.
+	Nonsense                    // a sense codon becomes a stop codon.
+	StopLost                    // a stop codon becomes a sense codon.
+)
+
+var effectNames = [...]string{
+	OutsideCDS:    "OutsideCDS",
+	Synonymous:    "Synonymous",
+	NonSynonymous: "NonSynonymous",
+	Nonsense:      "Nonsense",
+	StopLost:      "StopLost",
+}
+
+// String returns the name of the constant of the effect.
+func (e Effect) String() string {
+	if e < 0 || int(e) >= len(effectNames) {
+		return fmt.Sprintf("Effect(%d)", int(e))
+	}
+	return effectNames[e]
+}
+
+// VariantEffect returns the effect of substituting the base at the genome index pos (0-based)
+// by the alt base of the positive strand, in the first gene of the GFF records covering it.
+// The codon is read in the transcription direction of the gene,
+// so the alt base is complemented for a negative strand gene.
+// It returns OutsideCDS if no gene covers the site,
+// and an error if the index is out of the genome, the alt base or the codon is ambiguous,
+// or the site is in a partial codon.
+func VariantEffect(genome []byte, recs []*gff.Record, gc *taxonomy.GeneticCode, pos int, alt byte) (Effect, error) {
+	if err := checkGeneticCode(gc, Options{}); err != nil {
+		return OutsideCDS, err
+	}
+
+	a := variantEffect(genome, gffGenes(genome, recs, Options{}), gc, pos, alt)
+	return a.effect, a.err
+}
+
+// Variant is a single nucleotide substitution.
+type Variant struct {
+	Pos int  // genome index (0-based), i.e. the POS of a VCF record minus one.
+	Alt byte // alternative base of the positive strand.
+}
+
+// AnnotatedVariant is a variant with its effect.
+type AnnotatedVariant struct {
+	Variant
+	Gene         string // gene covering the site, empty if none.
+	CodonPos     int    // position (1, 2 or 3) of the site in the codon, 0 if OutsideCDS.
+	RefAA, AltAA byte   // amino acids of the reference and alternative codons, 0 if OutsideCDS.
+	Effect       Effect
+	Err          error // why the variant can not be classified, see VariantEffect.
+}
+
+// AnnotateVariants returns the effect of each variant, as VariantEffect.
+// The genome is profiled once, and the codon of a variant is read from the profile;
+// only the variants at overlapping, start or stop codon sites
+// are classified from the GFF records.
+// It panics if the genetic code is nil or has no fourfold codons.
+func AnnotateVariants(genome []byte, recs []*gff.Record, gc *taxonomy.GeneticCode, variants []Variant) []AnnotatedVariant {
+	profile := ProfileGenomeWithOptions(genome, recs, gc, Options{})
+	var genes []cds // the genes of the records, when needed.
+
+	annotated := make([]AnnotatedVariant, len(variants))
+	for i, v := range variants {
+		a, ok := profileVariant(profile, gc, v)
+		if !ok {
+			if genes == nil {
+				genes = gffGenes(genome, recs, Options{})
+			}
+			a = variantEffect(genome, genes, gc, v.Pos, v.Alt)
+		}
+		annotated[i] = AnnotatedVariant{
+			Variant:  v,
+			Gene:     a.gene,
+			CodonPos: a.codonPos,
+			RefAA:    a.refAA,
+			AltAA:    a.altAA,
+			Effect:   a.effect,
+			Err:      a.err,
+		}
+	}
+	return annotated
+}
+
+// profileVariant classifies a variant from the codon of its site in the profile.
+// It returns false if the codon can not be read from the profile.
+func profileVariant(profile []Pos, gc *taxonomy.GeneticCode, v Variant) (a codonChange, ok bool) {
+	p, found := Lookup(profile, v.Pos)
+	if !found || baseIndex(v.Alt) < 0 {
+		return a, false
+	}
+	if p.Type == NonCoding {
+		return a, true
+	}
+
+	k := codonPosition(p.Type)
+	codon, found := PositionCodon(profile, v.Pos)
+	if k == 0 || !found {
+		return a, false
+	}
+
+	b := bytes.ToUpper([]byte{v.Alt})[0]
+	if p.Strand == '-' {
+		b = seq.Complement([]byte{b})[0]
+	}
+	a.gene = p.Gene
+	a.codonPos = k
+	a.classify(gc, []byte(codon), k-1, b)
+	return a, true
+}
+
+// variantEffect classifies a substitution in the first of the genes covering it.
+func variantEffect(genome []byte, genes []cds, gc *taxonomy.GeneticCode, pos int, alt byte) (a codonChange) {
+	if pos < 0 || pos >= len(genome) {
+		a.err = fmt.Errorf("profiling: variant index %d is out of the genome of length %d", pos, len(genome))
+		return
+	}
+	if baseIndex(alt) < 0 {
+		a.err = fmt.Errorf("profiling: variant at %d has an ambiguous base %q", pos, alt)
+		return
+	}
+
+	for _, g := range genes {
+		codon, k, found := g.codonAt(genome, pos)
+		if !found {
+			continue
+		}
+		a.gene = g.name
+		if codon == nil {
+			a.err = fmt.Errorf("profiling: variant at %d is in a partial codon of %s", pos, g.name)
+			return
+		}
+		b := bytes.ToUpper([]byte{alt})[0]
+		if g.reverse {
+			b = seq.Complement([]byte{b})[0]
+		}
+		a.codonPos = k + 1
+		a.classify(gc, codon, k, b)
+		return
+	}
+	return
+}
+
+// codonChange is the change of a codon by a substitution.
+type codonChange struct {
+	gene         string
+	codonPos     int // position (1, 2 or 3) of the site in the codon.
+	refAA, altAA byte
+	effect       Effect
+	err          error
+}
+
+// classify sets the amino acids and the effect of substituting the base k (0, 1 or 2)
+// of the codon, in the transcription direction.
+func (a *codonChange) classify(gc *taxonomy.GeneticCode, codon []byte, k int, alt byte) {
+	if !isUnambiguous(codon) {
+		a.err = fmt.Errorf("profiling: codon %s of %s is ambiguous", codon, a.gene)
+		return
+	}
+
+	mutant := []byte(string(codon))
+	mutant[k] = alt
+	a.refAA = gc.Translate(codon)[0]
+	a.altAA = gc.Translate(mutant)[0]
+	switch {
+	case a.refAA == a.altAA:
+		a.effect = Synonymous
+	case a.altAA == '*':
+		a.effect = Nonsense
+	case a.refAA == '*':
+		a.effect = StopLost
+	default:
+		a.effect = NonSynonymous
+	}
+}
+
+// codonAt returns the codon of the gene, in the transcription direction,
+// covering the genome index, and the position (0, 1 or 2) of the index in the codon.
+// It returns false if the gene does not cover the index,
+// and a nil codon if the index is in a partial codon.
+func (g cds) codonAt(genome []byte, index int) (codon []byte, k int, found bool) {
+	j := -1
+	for i, gi := range g.indices {
+		if gi == index {
+			j = i
+			break
+		}
+	}
+	if j < 0 {
+		return nil, 0, false
+	}
+
+	nucl, phase := g.sequence(genome)
+	if g.reverse {
+		j = len(nucl) - 1 - j
+	}
+	if j < phase {
+		return nil, 0, true
+	}
+	start := j - (j-phase)%3
+	if start+3 > len(nucl) {
+		return nil, 0, true
+	}
+	return nucl[start : start+3], j - start, true
+}
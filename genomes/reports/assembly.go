@@ -0,0 +1,55 @@
+package reports
+
+import "io"
+
+// Assembly is a record of assembly_summary.txt,
+// e.g. assembly_summary_refseq.txt in ftp://ftp.ncbi.nlm.nih.gov/genomes/ASSEMBLY_REPORTS.
+type Assembly struct {
+	Accession         string // assembly accession, e.g. GCF_000005845.2.
+	BioProject        string // BioProject accession, e.g. PRJNA57779.
+	BioSample         string // BioSample accession.
+	TaxId             string // Taxonomy ID.
+	SpeciesTaxId      string // Taxonomy ID of the species.
+	OrganismName      string // Organism name, e.g. Escherichia coli str. K-12 substr. MG1655.
+	InfraspecificName string // e.g. strain=K-12.
+	RefSeqCategory    string // e.g. reference genome.
+	VersionStatus     string // latest, replaced or suppressed.
+	AssemblyLevel     string // Complete Genome, Chromosome, Scaffold or Contig.
+	AsmName           string // assembly name, e.g. ASM584v2.
+	SeqRelDate        string // release date of the sequences, e.g. 2013/09/26.
+	FtpPath           string // folder path to the NCBI ftp.
+}
+
+// ReadAssemblySummary reads assembly_summary.txt.
+func ReadAssemblySummary(f io.Reader) (assemblies []Assembly, err error) {
+	rd, err := newReportReader(f, "assembly_summary")
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := rd.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fields := range records {
+		a := Assembly{}
+		a.Accession = rd.field(fields, "assembly_accession")
+		a.BioProject = rd.field(fields, "bioproject")
+		a.BioSample = rd.field(fields, "biosample")
+		a.TaxId = rd.field(fields, "taxid")
+		a.SpeciesTaxId = rd.field(fields, "species_taxid")
+		a.OrganismName = rd.field(fields, "organism_name")
+		a.InfraspecificName = rd.field(fields, "infraspecific_name")
+		a.RefSeqCategory = rd.field(fields, "refseq_category")
+		a.VersionStatus = rd.field(fields, "version_status")
+		a.AssemblyLevel = rd.field(fields, "assembly_level")
+		a.AsmName = rd.field(fields, "asm_name")
+		a.SeqRelDate = rd.field(fields, "seq_rel_date")
+		a.FtpPath = rd.field(fields, "ftp_path")
+
+		assemblies = append(assemblies, a)
+	}
+
+	return
+}
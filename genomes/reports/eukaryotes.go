@@ -0,0 +1,62 @@
+package reports
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// EukaryoteStrain is a record of eukaryotes.txt.
+type EukaryoteStrain struct {
+	ProjectId string  // BioProject ID.
+	Name      string  // Organism name.
+	TaxId     string  // Taxonomy ID.
+	Group     string  // Group, e.g. Fungi.
+	SubGroup  string  // Subgroup, e.g. Ascomycetes.
+	Size      float64 // Genome size in Mb.
+	GC        float64 // GC content in percent.
+	Assembly  string  // Assembly accession.
+	Status    string  // Assembly level, e.g. Chromosome, Scaffold or Contig.
+}
+
+// ReadEukaryotes reads eukaryotes.txt.
+func ReadEukaryotes(f io.Reader) (strains []EukaryoteStrain, err error) {
+	rd, err := newReportReader(f, "eukaryotes")
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := rd.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fields := range records {
+		s := EukaryoteStrain{}
+		s.Name = rd.field(fields, "Organism/Name")
+		s.TaxId = rd.field(fields, "TaxID")
+		s.ProjectId = rd.field(fields, "BioProject ID")
+		s.Group = rd.field(fields, "Group")
+		s.SubGroup = rd.field(fields, "SubGroup")
+		s.Assembly = rd.field(fields, "Assembly Accession")
+		s.Status = rd.field(fields, "Status")
+		if s.Size, err = parseFloat(rd.field(fields, "Size (Mb)")); err != nil {
+			return nil, fmt.Errorf("eukaryotes: %s: size: %w", s.Name, err)
+		}
+		if s.GC, err = parseFloat(rd.field(fields, "GC%")); err != nil {
+			return nil, fmt.Errorf("eukaryotes: %s: GC%%: %w", s.Name, err)
+		}
+
+		strains = append(strains, s)
+	}
+
+	return
+}
+
+// parseFloat parses a numeric column, where "-" or an empty value means 0.
+func parseFloat(s string) (float64, error) {
+	if s == "-" || s == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
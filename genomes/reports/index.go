@@ -0,0 +1,35 @@
+package reports
+
+// IndexByTaxID returns the strains keyed by taxonomy ID,
+// since several strains can share a TaxId.
+func IndexByTaxID(strains []Strain) map[string][]Strain {
+	m := make(map[string][]Strain)
+	for _, s := range strains {
+		m[s.TaxId] = append(m[s.TaxId], s)
+	}
+	return m
+}
+
+// IndexByProjectID returns the strains keyed by BioProject ID.
+// If several strains share a BioProject ID, the last one is kept.
+func IndexByProjectID(strains []Strain) map[string]Strain {
+	m := make(map[string]Strain)
+	for _, s := range strains {
+		m[s.ProjectId] = s
+	}
+	return m
+}
+
+// FindStrainByAccession returns the first strain with a genome of the accession,
+// ignoring the accession version, e.g. NC_000913.3 matches NC_000913.
+func FindStrainByAccession(strains []Strain, acc string) (Strain, bool) {
+	acc, _ = splitVersion(acc)
+	for _, s := range strains {
+		for _, g := range s.Genomes {
+			if a, _ := splitVersion(g.Accession); a == acc {
+				return s, true
+			}
+		}
+	}
+	return Strain{}, false
+}
@@ -0,0 +1,59 @@
+package reports
+
+import "strings"
+
+// Level is the assembly level of a genome,
+// ordered from the least to the most complete,
+// so that e.g. level >= Chromosome selects the chromosome-level assemblies.
+type Level int
+
+const (
+	Unknown Level = iota // unrecognized or missing status.
+	Contig
+	Scaffold
+	Chromosome
+	CompleteGenome
+)
+
+var levelNames = [...]string{
+	Unknown:        "Unknown",
+	Contig:         "Contig",
+	Scaffold:       "Scaffold",
+	Chromosome:     "Chromosome",
+	CompleteGenome: "Complete Genome",
+}
+
+// String returns the status of the level as written in the reports,
+// e.g. "Complete Genome".
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(levelNames) {
+		return levelNames[Unknown]
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the assembly level of a status or an assembly_level
+// of the reports, ignoring case, spacing and underscores,
+// e.g. "Complete Genome", "complete" or "Gapless Chromosome".
+// It returns Unknown for an unrecognized status.
+func ParseLevel(status string) Level {
+	s := strings.ToLower(strings.Join(strings.FieldsFunc(status, func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-' || r == '\t'
+	}), " "))
+	switch s {
+	case "complete genome", "complete":
+		return CompleteGenome
+	case "chromosome", "gapless chromosome", "chromosome with gaps":
+		return Chromosome
+	case "scaffold", "scaffolds or contigs":
+		return Scaffold
+	case "contig", "contigs":
+		return Contig
+	}
+	return Unknown
+}
+
+// AssemblyLevel returns the assembly level of the strain from its status.
+func (s Strain) AssemblyLevel() Level {
+	return ParseLevel(s.Status)
+}
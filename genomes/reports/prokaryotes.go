@@ -3,6 +3,7 @@ package reports
 import (
 	"bufio"
 	"io"
+	"sort"
 	"strings"
 )
 
@@ -11,92 +12,263 @@ import (
 // ftp://ftp.ncbi.nlm.nih.gov/genomes/GENOME_REPORTS
 
 // Strain information.
+// In JSON, the sequences and profiles of the genomes are omitted if empty,
+// so that the metadata of strains can be serialized compactly.
 type Strain struct {
-	ProjectId   string   // BioProject ID.
-	Name        string   // Strain name.
-	TaxId       string   // Taxonomy ID.
-	Genomes     []Genome // Genome RefSeq accessions.
-	Path        string   // folder path to the NCBI ftp.
-	GeneticCode string   // Genetic codon table ID.
-	Status      string   // Status, complete or not.
+	ProjectId   string   `json:"project_id"`   // BioProject ID.
+	Name        string   `json:"name"`         // Strain name.
+	TaxId       string   `json:"tax_id"`       // Taxonomy ID.
+	Genomes     []Genome `json:"genomes"`      // Genome RefSeq accessions.
+	Path        string   `json:"path"`         // folder path to the NCBI ftp.
+	GeneticCode string   `json:"genetic_code"` // Genetic codon table ID.
+	Status      string   `json:"status"`       // Status, complete or not.
 }
 
 type Genome struct {
-	Accession  string
-	Replicon   string
-	Length     int
-	Seq        []byte
-	PosProfile []byte
+	Accession  string `json:"accession"`             // RefSeq accession without version, e.g. NC_000913.
+	Version    string `json:"version,omitempty"`     // accession version, e.g. 3, or empty if unknown.
+	Replicon   string `json:"replicon"`              // Chromosome or Plasmid.
+	Length     int    `json:"length,omitempty"`      // sequence length.
+	Seq        []byte `json:"seq,omitempty"`         // nucleotide sequence.
+	PosProfile []byte `json:"pos_profile,omitempty"` // position types, see profiling.ProfileToBytes.
 }
 
-// Read prokaryotes.txt
+// Read prokaryotes.txt, and panics on any parse error.
 func ReadProkaryotes(f io.Reader) (strains []Strain) {
-	// create a buffer reader.
-	rd := bufio.NewReader(f)
-
-	// read the first commented line to
-	// determine the field names.
-	nameMap := make(map[string]int)
-	if r1, _, err := rd.ReadRune(); err == nil {
-		if r1 == '#' {
-			line, err := rd.ReadString('\n')
-			if err != nil {
-				panic(err)
-			} else {
-				names := strings.Split(strings.TrimSpace(line), "\t")
-				for i := 0; i < len(names); i++ {
-					nameMap[names[i]] = i
-				}
-			}
-		}
-	} else {
+	strains, err := ReadProkaryotesSafe(f)
+	if err != nil {
 		panic(err)
 	}
 
-	records := [][]string{}
+	return
+}
+
+// ReadProkaryotesSafe reads prokaryotes.txt,
+// and returns an error if it can not be parsed.
+func ReadProkaryotesSafe(f io.Reader) (strains []Strain, err error) {
+	return ReadProkaryotesFiltered(f)
+}
+
+// ReadProkaryotesFiltered reads prokaryotes.txt,
+// and returns only the strains whose status is one of the statuses,
+// e.g. "Complete Genome". If no status is given, it returns all strains.
+func ReadProkaryotesFiltered(f io.Reader, statuses ...string) (strains []Strain, err error) {
+	keep := make(map[string]bool)
+	for _, status := range statuses {
+		keep[status] = true
+	}
+
+	err = IterProkaryotes(f, func(s Strain) error {
+		if len(keep) == 0 || keep[s.Status] {
+			strains = append(strains, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// ReadProkaryotesMalformed is like ReadProkaryotesSafe,
+// but also returns the number of malformed rows,
+// which have fewer fields than the header and whose missing fields are empty.
+func ReadProkaryotesMalformed(f io.Reader) (strains []Strain, malformed int, err error) {
+	malformed, err = iterProkaryotes(f, false, func(s Strain) error {
+		strains = append(strains, s)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return
+}
+
+// IterProkaryotes parses prokaryotes.txt one line at a time,
+// and calls fn with each strain.
+// It stops and returns the error if fn returns an error.
+func IterProkaryotes(f io.Reader, fn func(Strain) error) error {
+	_, err := iterProkaryotes(f, false, fn)
+	return err
+}
+
+// ReadProkaryotesQuoted is like ReadProkaryotesSafe,
+// but parses the records as tab-delimited CSV with quoted fields,
+// e.g. for a report exported from a spreadsheet,
+// where a quoted organism name may contain tabs.
+func ReadProkaryotesQuoted(f io.Reader) (strains []Strain, err error) {
+	_, err = iterProkaryotes(f, true, func(s Strain) error {
+		strains = append(strains, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// iterProkaryotes is IterProkaryotes returning the number of malformed rows,
+// and parsing quoted fields if quoted is true.
+func iterProkaryotes(f io.Reader, quoted bool, fn func(Strain) error) (malformed int, err error) {
+	rd, err := newReportReader(f, "prokaryotes")
+	if err != nil {
+		return 0, err
+	}
+	if quoted {
+		rd.parseQuoted()
+	}
+
 	for {
-		line, err := rd.ReadString('\n')
+		fields, err := rd.Read()
 		if err != nil {
-			if err != io.EOF {
-				panic(err)
-			} else {
-				break
-			}
-		} else {
-			// continue, if it is a comment line.
-			if line[0] == '#' {
-				continue
+			if err == io.EOF {
+				return rd.malformed, nil
 			}
-			fields := strings.Split(strings.TrimSpace(line), "\t")
-			records = append(records, fields)
+			return rd.malformed, err
+		}
+
+		if err := fn(parseStrain(rd, fields)); err != nil {
+			return rd.malformed, err
 		}
 	}
+}
+
+// parseStrain parses a record of prokaryotes.txt.
+func parseStrain(rd *reportReader, fields []string) Strain {
+	s := Strain{}
+	s.Name = rd.field(fields, "Organism/Name")
+	s.TaxId = rd.field(fields, "TaxID")
+	s.ProjectId = rd.field(fields, "BioProject ID")
+	s.Path = rd.field(fields, "FTP Path")
+	s.Status = rd.field(fields, "Status")
+
+	chromosomes := rd.field(fields, "Chromosomes/RefSeq")
+	s.Genomes = appendGenomes(s.Genomes, chromosomes, "Chromosome")
+
+	// older reports do not have the plasmid column.
+	plasmids := rd.field(fields, "Plasmids/RefSeq")
+	s.Genomes = appendGenomes(s.Genomes, plasmids, "Plasmid")
+
+	return s
+}
+
+// appendGenomes appends the genomes of a column of accessions,
+// sorted by accession so that the order is the same between runs.
+func appendGenomes(genomes []Genome, column, replicon string) []Genome {
+	versions := parseAccessions(column)
+	accessions := make([]string, 0, len(versions))
+	for acc := range versions {
+		accessions = append(accessions, acc)
+	}
+	sort.Strings(accessions)
+
+	for _, acc := range accessions {
+		genomes = append(genomes,
+			Genome{Accession: acc, Version: versions[acc], Replicon: replicon})
+	}
+	return genomes
+}
+
+// parseAccessions parses a comma-separated list of RefSeq accessions,
+// and returns the version of each accession, removing redundant accessions.
+// An accession listed with several versions keeps the first one.
+func parseAccessions(column string) map[string]string {
+	m := make(map[string]string)
+	for _, g := range strings.Split(column, ",") {
+		acc, version := splitVersion(strings.TrimSpace(g))
+		if acc == "-" || acc == "" {
+			continue
+		}
+		if _, found := m[acc]; !found {
+			m[acc] = version
+		}
+	}
+	return m
+}
+
+// splitVersion splits an accession into the accession without version and the version,
+// e.g. "NC_000913.3" into "NC_000913" and "3".
+func splitVersion(acc string) (string, string) {
+	if i := strings.Index(acc, "."); i >= 0 {
+		return acc[:i], acc[i+1:]
+	}
+	return acc, ""
+}
 
-	for _, fields := range records {
-		s := Strain{}
-		s.Name = fields[nameMap["Organism/Name"]]
-		s.TaxId = fields[nameMap["TaxID"]]
-		s.ProjectId = fields[nameMap["BioProject ID"]]
-		s.Path = fields[nameMap["FTP Path"]]
-		s.Status = fields[nameMap["Status"]]
-
-		chromosomes := fields[nameMap["Chromosomes/RefSeq"]]
-		// remove redundant.
-		m := make(map[string]bool)
-		for _, g := range strings.Split(chromosomes, ",") {
-			acc := strings.Split(strings.TrimSpace(g), ".")[0]
-			m[acc] = true
+// VersionedAccession returns the accession with its version, e.g. NC_000913.3,
+// or the accession alone if the version is unknown.
+func (g Genome) VersionedAccession() string {
+	if g.Version == "" {
+		return g.Accession
+	}
+	return g.Accession + "." + g.Version
+}
+
+// AssignGeneticCodes fills the genetic code of each strain
+// from codes, a map of taxonomy ID to genetic code table ID,
+// e.g. built from the GeneticCode of taxonomy.ReadTaxas.
+// Strains whose TaxId is not in codes are left unchanged.
+func AssignGeneticCodes(strains []Strain, codes map[string]string) {
+	for i := range strains {
+		if gc, found := codes[strains[i].TaxId]; found {
+			strains[i].GeneticCode = gc
 		}
+	}
+}
+
+// prokaryotesColumns are the columns written by WriteProkaryotes.
+var prokaryotesColumns = []string{
+	"Organism/Name",
+	"TaxID",
+	"BioProject ID",
+	"Status",
+	"Chromosomes/RefSeq",
+	"Plasmids/RefSeq",
+	"FTP Path",
+}
+
+// WriteProkaryotes writes strains in the tab-delimited format of prokaryotes.txt,
+// with a #-prefixed header line, so that it can be read by ReadProkaryotes.
+func WriteProkaryotes(w io.Writer, strains []Strain) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString("#" + strings.Join(prokaryotesColumns, "\t") + "\n"); err != nil {
+		return err
+	}
 
-		for acc, _ := range m {
-			if acc != "-" {
-				s.Genomes = append(s.Genomes,
-					Genome{Accession: acc, Replicon: "Chromosome"})
+	for _, s := range strains {
+		var chromosomes, plasmids []string
+		for _, g := range s.Genomes {
+			if g.Replicon == "Plasmid" {
+				plasmids = append(plasmids, g.VersionedAccession())
+			} else {
+				chromosomes = append(chromosomes, g.VersionedAccession())
 			}
 		}
 
-		strains = append(strains, s)
+		fields := []string{
+			s.Name,
+			s.TaxId,
+			s.ProjectId,
+			s.Status,
+			joinAccessions(chromosomes),
+			joinAccessions(plasmids),
+			s.Path,
+		}
+		if _, err := bw.WriteString(strings.Join(fields, "\t") + "\n"); err != nil {
+			return err
+		}
 	}
 
-	return
+	return bw.Flush()
+}
+
+// joinAccessions joins accessions by commas, or returns "-" if there is none.
+func joinAccessions(accs []string) string {
+	if len(accs) == 0 {
+		return "-"
+	}
+	return strings.Join(accs, ",")
 }
@@ -0,0 +1,153 @@
+package reports
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// reportReader reads a tab-delimited report of GENOME_REPORTS,
+// whose leading commented lines end with the field names.
+type reportReader struct {
+	name    string // report name used in errors.
+	rd      *bufio.Reader
+	names   []string
+	nameMap map[string]int
+	lineNum int
+
+	// malformed is the number of records with fewer fields than the header.
+	malformed int
+
+	// csv, if not nil, reads the records with quoted fields.
+	csv *csv.Reader
+}
+
+// newReportReader reads the header line of a report.
+func newReportReader(f io.Reader, name string) (*reportReader, error) {
+	r := &reportReader{name: name, rd: bufio.NewReader(f), nameMap: make(map[string]int)}
+
+	// read the first commented line to
+	// determine the field names.
+	r1, _, err := r.rd.ReadRune()
+	if err != nil {
+		if err == io.EOF {
+			err = fmt.Errorf("%s: missing header line", name)
+		}
+		return nil, err
+	}
+	if r1 != '#' {
+		return nil, fmt.Errorf("%s: missing header line", name)
+	}
+	line, err := r.rd.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	r.lineNum = 1
+	// some reports, e.g. assembly_summary.txt, start with comments,
+	// so the field names are on the last of the leading commented lines.
+	for {
+		next, err := r.rd.Peek(1)
+		if err != nil || next[0] != '#' {
+			break
+		}
+		if line, err = r.rd.ReadString('\n'); err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = line[1:]
+		r.lineNum++
+	}
+	r.names = strings.Split(strings.TrimSpace(line), "\t")
+	for i := 0; i < len(r.names); i++ {
+		r.nameMap[r.names[i]] = i
+	}
+
+	return r, nil
+}
+
+// parseQuoted makes the reader parse the records as tab-delimited CSV,
+// so that quoted fields may contain tabs, newlines and quotes,
+// e.g. in a report exported from a spreadsheet.
+func (r *reportReader) parseQuoted() {
+	r.csv = csv.NewReader(r.rd)
+	r.csv.Comma = '\t'
+	r.csv.Comment = '#'
+	r.csv.LazyQuotes = true
+	r.csv.FieldsPerRecord = -1
+}
+
+// Read returns the fields of the next record, or io.EOF at the end of the report.
+// Blank and comment lines are skipped.
+// A record may have fewer fields than the header, see field.
+func (r *reportReader) Read() (fields []string, err error) {
+	if r.csv != nil {
+		return r.readQuoted()
+	}
+
+	for {
+		line, err := r.rd.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		// the last line may not end with a newline,
+		// so parse it before returning EOF.
+		if line != "" {
+			r.lineNum++
+			// skip blank and comment lines.
+			if strings.TrimSpace(line) != "" && line[0] != '#' {
+				fields := strings.Split(strings.TrimRight(line, "\r\n"), "\t")
+				// ragged rows occur in the reports,
+				// their missing fields are read as empty strings.
+				if len(fields) < len(r.names) {
+					r.malformed++
+				}
+				return fields, nil
+			}
+		}
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+	}
+}
+
+// readQuoted returns the fields of the next CSV record.
+func (r *reportReader) readQuoted() (fields []string, err error) {
+	fields, err = r.csv.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("%s: %w", r.name, err)
+	}
+	r.lineNum++
+	if len(fields) < len(r.names) {
+		r.malformed++
+	}
+	return fields, nil
+}
+
+// ReadAll returns the fields of all remaining records.
+func (r *reportReader) ReadAll() (records [][]string, err error) {
+	for {
+		fields, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		records = append(records, fields)
+	}
+	return
+}
+
+// field returns the value of the named column,
+// or an empty string if the report does not have the column.
+func (r *reportReader) field(fields []string, name string) string {
+	i, found := r.nameMap[name]
+	if !found || i >= len(fields) {
+		return ""
+	}
+	return fields[i]
+}
@@ -0,0 +1,65 @@
+package reports
+
+import (
+	"path"
+	"strings"
+)
+
+// bacteriaURL is the folder of the legacy per-strain genome directories,
+// where genome files are named by replicon accessions, e.g. NC_000913.fna.
+const bacteriaURL = "https://ftp.ncbi.nlm.nih.gov/genomes/Bacteria"
+
+// GenomeURL returns the URL of the genome FASTA file of the replicon accession,
+// or an empty string if the strain has no path.
+func (s Strain) GenomeURL(acc string) string {
+	return s.fileURL(acc, ".fna", "_genomic.fna.gz")
+}
+
+// GFFURL returns the URL of the GFF file of the replicon accession,
+// or an empty string if the strain has no path.
+func (s Strain) GFFURL(acc string) string {
+	return s.fileURL(acc, ".gff", "_genomic.gff.gz")
+}
+
+// PTTURL returns the URL of the .ptt file of the replicon accession.
+// The assembly directories do not have .ptt files,
+// so it returns an empty string for them, as for a strain without path.
+func (s Strain) PTTURL(acc string) string {
+	return s.fileURL(acc, ".ptt", "")
+}
+
+// baseURL returns the URL of the strain folder,
+// using HTTPS since NCBI deprecated plain FTP.
+// It returns an empty string if the strain has no path.
+func (s Strain) baseURL() string {
+	p := strings.TrimRight(s.Path, "/")
+	switch {
+	case p == "":
+		return ""
+	case strings.HasPrefix(p, "ftp://"):
+		return "https://" + strings.TrimPrefix(p, "ftp://")
+	case strings.HasPrefix(p, "http://"), strings.HasPrefix(p, "https://"):
+		return p
+	}
+	return bacteriaURL + "/" + strings.TrimLeft(p, "/")
+}
+
+// fileURL returns the URL of a file in the strain folder.
+// A legacy folder has a file per replicon named by its accession and the suffix,
+// while an assembly folder (GCA_ or GCF_) has a single file for all replicons,
+// named by the assembly and the assemblySuffix.
+// It returns an empty string if the strain has no path.
+func (s Strain) fileURL(acc, suffix, assemblySuffix string) string {
+	base := s.baseURL()
+	if base == "" {
+		return ""
+	}
+	name := path.Base(base)
+	if strings.HasPrefix(name, "GCA_") || strings.HasPrefix(name, "GCF_") {
+		if assemblySuffix == "" {
+			return ""
+		}
+		return base + "/" + name + assemblySuffix
+	}
+	return base + "/" + strings.Split(acc, ".")[0] + suffix
+}
@@ -0,0 +1,53 @@
+package reports
+
+import (
+	"fmt"
+	"io"
+)
+
+// VirusStrain is a record of viruses.txt.
+type VirusStrain struct {
+	ProjectId string  // BioProject ID.
+	Name      string  // Organism name.
+	TaxId     string  // Taxonomy ID.
+	Group     string  // Group, e.g. dsDNA viruses, no RNA stage.
+	SubGroup  string  // Subgroup, e.g. Caudovirales.
+	Size      float64 // Genome size in Kb.
+	GC        float64 // GC content in percent.
+	Host      string  // Host, e.g. bacteria.
+	Status    string  // Status, e.g. Complete.
+}
+
+// ReadViruses reads viruses.txt.
+func ReadViruses(f io.Reader) (strains []VirusStrain, err error) {
+	rd, err := newReportReader(f, "viruses")
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := rd.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fields := range records {
+		s := VirusStrain{}
+		s.Name = rd.field(fields, "Organism/Name")
+		s.TaxId = rd.field(fields, "TaxID")
+		s.ProjectId = rd.field(fields, "BioProject ID")
+		s.Group = rd.field(fields, "Group")
+		s.SubGroup = rd.field(fields, "SubGroup")
+		s.Host = rd.field(fields, "Host")
+		s.Status = rd.field(fields, "Status")
+		if s.Size, err = parseFloat(rd.field(fields, "Size (Kb)")); err != nil {
+			return nil, fmt.Errorf("viruses: %s: size: %w", s.Name, err)
+		}
+		if s.GC, err = parseFloat(rd.field(fields, "GC%")); err != nil {
+			return nil, fmt.Errorf("viruses: %s: GC%%: %w", s.Name, err)
+		}
+
+		strains = append(strains, s)
+	}
+
+	return
+}
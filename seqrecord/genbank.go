@@ -0,0 +1,300 @@
+package seqrecord
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A GenBankRecord contains a replicon sequence
+// and its CDS features in a GenBank flat file.
+type GenBankRecord struct {
+	Id  string // accession with version, e.g. NC_000913.3.
+	Seq []byte // nucleotide sequence.
+	CDS []Ptt  // CDS features.
+}
+
+// ReadGenBank reads all records of a GenBank flat file (.gbk or .gbff),
+// extracting the coordinates, strands and qualifiers of CDS features.
+// CDS features on remote sequences or on both strands are skipped.
+func ReadGenBank(r io.Reader) (records []GenBankRecord, err error) {
+	rd := bufio.NewReader(r)
+
+	var rec *GenBankRecord
+	var section string   // current section, e.g. FEATURES or ORIGIN.
+	var feature []string // lines of the current feature.
+	lineNum := 0
+
+	flushFeature := func() error {
+		if len(feature) == 0 {
+			return nil
+		}
+		defer func() { feature = nil }()
+		ptt, ok, err := parseCDS(feature)
+		if err != nil {
+			return fmt.Errorf("genbank line %d: %w", lineNum, err)
+		}
+		if ok {
+			rec.CDS = append(rec.CDS, ptt)
+		}
+		return nil
+	}
+
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" && err == io.EOF {
+			break
+		}
+		lineNum++
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "LOCUS"):
+			records = append(records, GenBankRecord{})
+			rec = &records[len(records)-1]
+			if fields := strings.Fields(line); len(fields) > 1 {
+				rec.Id = fields[1]
+			}
+			section = "LOCUS"
+		case rec == nil:
+			// skip lines before the first record.
+		case strings.HasPrefix(line, "//"):
+			if err := flushFeature(); err != nil {
+				return nil, err
+			}
+			rec = nil
+			section = ""
+		case strings.HasPrefix(line, "VERSION"):
+			if fields := strings.Fields(line); len(fields) > 1 {
+				rec.Id = fields[1]
+			}
+		case strings.HasPrefix(line, "FEATURES"):
+			section = "FEATURES"
+		case strings.HasPrefix(line, "ORIGIN"):
+			if err := flushFeature(); err != nil {
+				return nil, err
+			}
+			section = "ORIGIN"
+		case section == "FEATURES":
+			if len(line) > 5 && line[5] != ' ' {
+				// a new feature key starts at column 6.
+				if err := flushFeature(); err != nil {
+					return nil, err
+				}
+			} else if len(line) > 0 && line[0] != ' ' {
+				// a new section after the features.
+				if err := flushFeature(); err != nil {
+					return nil, err
+				}
+				section = ""
+				continue
+			}
+			feature = append(feature, line)
+		case section == "ORIGIN":
+			for _, b := range []byte(line) {
+				if (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') {
+					rec.Seq = append(rec.Seq, b)
+				}
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if rec != nil {
+		if err := flushFeature(); err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
+
+// parseCDS parses the lines of a feature,
+// and returns false if it is not a CDS feature on a local sequence.
+func parseCDS(lines []string) (ptt Ptt, ok bool, err error) {
+	if fields := strings.Fields(lines[0]); len(fields) == 0 || fields[0] != "CDS" {
+		return
+	}
+
+	// the location may continue on the following lines,
+	// until the first qualifier.
+	var loc string
+	var qualifiers []string
+	for _, l := range lines {
+		value := ""
+		if len(l) > 21 {
+			value = strings.TrimSpace(l[21:])
+		}
+		if strings.HasPrefix(value, "/") {
+			qualifiers = append(qualifiers, value)
+		} else if len(qualifiers) > 0 {
+			// continuation of a qualifier value.
+			qualifiers[len(qualifiers)-1] += " " + value
+		} else {
+			loc += value
+		}
+	}
+
+	if strings.Contains(loc, ":") {
+		// remote sequence.
+		return
+	}
+	segments, strand, err := parseLocation(loc)
+	if err != nil {
+		return
+	}
+	if strand == "" {
+		// trans-spliced on both strands.
+		return
+	}
+
+	ptt.Loc = Location{From: segments[0].From, To: segments[len(segments)-1].To, Strand: strand}
+	if len(segments) > 1 {
+		ptt.Segments = segments
+	}
+	length := 0
+	for _, s := range segments {
+		if s.To >= s.From {
+			length += s.To - s.From + 1
+		}
+	}
+	if length >= 3 {
+		// number of amino acids, excluding the stop codon.
+		ptt.Length = length/3 - 1
+	}
+
+	for _, q := range qualifiers {
+		key, value := q[1:], ""
+		if i := strings.Index(q, "="); i > 0 {
+			key, value = q[1:i], strings.Trim(q[i+1:], "\"")
+		}
+		switch key {
+		case "protein_id":
+			ptt.PID = value
+		case "gene":
+			ptt.Gene = value
+		case "locus_tag":
+			ptt.SynonymCode = value
+		case "product":
+			ptt.Product = value
+		}
+	}
+
+	return ptt, true, nil
+}
+
+// parseLocation parses a GenBank feature location,
+// e.g. "complement(join(1..10,20..30))".
+// It returns the segments in the positive strand order,
+// and the strand ("+" or "-"), or "" if the segments are on both strands.
+func parseLocation(loc string) (segments []Location, strand string, err error) {
+	segments, err = parseSegments(strings.ReplaceAll(loc, " ", ""), "+")
+	if err != nil {
+		return nil, "", err
+	}
+	if len(segments) == 0 {
+		return nil, "", fmt.Errorf("empty location %q", loc)
+	}
+
+	strand = segments[0].Strand
+	for _, s := range segments {
+		if s.Strand != strand {
+			return segments, "", nil
+		}
+	}
+
+	// the segments are in the transcription order,
+	// so reverse them on the negative strand.
+	if strand == "-" {
+		for i, j := 0, len(segments)-1; i < j; i, j = i+1, j-1 {
+			segments[i], segments[j] = segments[j], segments[i]
+		}
+	}
+
+	return
+}
+
+// parseSegments parses a location into segments in the transcription order.
+func parseSegments(loc, strand string) (segments []Location, err error) {
+	switch {
+	case strings.HasPrefix(loc, "complement(") && strings.HasSuffix(loc, ")"):
+		inner, err := parseSegments(loc[len("complement("):len(loc)-1], complement(strand))
+		if err != nil {
+			return nil, err
+		}
+		// complement reverses the transcription order.
+		for i := len(inner) - 1; i >= 0; i-- {
+			segments = append(segments, inner[i])
+		}
+		return segments, nil
+	case strings.HasPrefix(loc, "join(") && strings.HasSuffix(loc, ")"):
+		return parseList(loc[len("join("):len(loc)-1], strand)
+	case strings.HasPrefix(loc, "order(") && strings.HasSuffix(loc, ")"):
+		return parseList(loc[len("order("):len(loc)-1], strand)
+	}
+
+	// a single span, "<1..>10", "5" or "5^6",
+	// where the partial markers are ignored.
+	terms := strings.Split(strings.NewReplacer("<", "", ">", "").Replace(loc), "..")
+	if len(terms) == 1 {
+		// a site between two bases.
+		terms[0] = strings.Split(terms[0], "^")[0]
+	}
+	from, err := strconv.Atoi(terms[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed location %q", loc)
+	}
+	to := from
+	if len(terms) > 1 {
+		if to, err = strconv.Atoi(terms[1]); err != nil {
+			return nil, fmt.Errorf("malformed location %q", loc)
+		}
+	}
+	return []Location{{From: from, To: to, Strand: strand}}, nil
+}
+
+// parseList parses a comma-separated list of locations,
+// splitting at the top-level commas only.
+func parseList(list, strand string) (segments []Location, err error) {
+	depth, start := 0, 0
+	for i := 0; i <= len(list); i++ {
+		if i < len(list) {
+			switch list[i] {
+			case '(':
+				depth++
+				continue
+			case ')':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		segs, err := parseSegments(list[start:i], strand)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segs...)
+		start = i + 1
+	}
+	return
+}
+
+func complement(strand string) string {
+	if strand == "-" {
+		return "+"
+	}
+	return "-"
+}
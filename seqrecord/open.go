@@ -0,0 +1,71 @@
+package seqrecord
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+// magic bytes starting the compressed streams.
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Open opens a file for reading,
+// decompressing it if it is gzip or bzip2-compressed
+// (e.g. the .fna.gz files of NCBI, or the .bz2 files of mirrors).
+// The compression is detected by the magic bytes, not by the file extension.
+// A zstd-compressed file is detected, but not supported, and returns an error.
+func Open(fileName string) (io.ReadCloser, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		f.Close()
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return readCloser{zr, multiCloser{zr, f}}, nil
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return readCloser{bzip2.NewReader(br), f}, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		f.Close()
+		return nil, fmt.Errorf("%s: zstd compression is not supported", fileName)
+	}
+	return readCloser{br, f}, nil
+}
+
+// readCloser reads from a reader, and closes a closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// multiCloser closes all closers, and returns the first error.
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() (err error) {
+	for _, c := range m {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
@@ -2,18 +2,20 @@ package seqrecord
 
 import (
 	"bufio"
+	"fmt"
 	"io"
-	"os"
 	"strconv"
 	"strings"
 )
 
 type Ptt struct {
 	Loc         Location
+	Segments    []Location // segments of a joined location, in the positive strand order.
 	Length      int
 	PID         string
 	Gene        string
 	SynonymCode string
+	Code        string
 	COG         string
 	Product     string
 }
@@ -22,49 +24,228 @@ type PttFile struct {
 	r io.ReadCloser
 }
 
+// NewPttFile opens a .ptt file, and panics if it can not be opened.
 func NewPttFile(fileName string) *PttFile {
-	p := PttFile{}
-	var err error
-	p.r, err = os.Open(fileName)
+	p, err := OpenPttFile(fileName)
 	if err != nil {
 		panic(err)
 	}
 
-	return &p
+	return p
+}
+
+// OpenPttFile opens a .ptt file for reading,
+// which may be gzip-compressed.
+func OpenPttFile(fileName string) (*PttFile, error) {
+	f, err := Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PttFile{r: f}, nil
+}
+
+// OpenRntFile opens a .rnt file for reading,
+// which describes the RNA genes (rRNA, tRNA, etc.) in the columns of a .ptt file,
+// and is read into Ptt records as a .ptt file.
+func OpenRntFile(fileName string) (*PttFile, error) {
+	return OpenPttFile(fileName)
+}
+
+// NewPttReader returns a PttFile reading the records of a .ptt file from r,
+// e.g. from memory; its Close does not close r.
+func NewPttReader(r io.Reader) *PttFile {
+	return &PttFile{r: io.NopCloser(r)}
 }
 
 func (p *PttFile) Close() {
 	p.r.Close()
 }
 
+// ReadAll reads all ptt records, and panics on any error.
 func (p *PttFile) ReadAll() (ptts []Ptt) {
+	ptts, err := p.ReadPtts()
+	if err != nil {
+		panic(err)
+	}
+
+	return
+}
+
+// ReadPtts reads all ptt records,
+// and returns an error if the file is malformed.
+func (p *PttFile) ReadPtts() (ptts []Ptt, err error) {
+	ptts, _, err = p.readPtts()
+	return
+}
+
+// ReadPttsValidated is like ReadPtts, but also returns an error,
+// with the line number, for each record that is invalid (see Ptt.Validate).
+// The invalid records are returned with the valid ones.
+func (p *PttFile) ReadPttsValidated() (ptts []Ptt, invalid []error, err error) {
+	ptts, lineNums, err := p.readPtts()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, ptt := range ptts {
+		if e := ptt.Validate(); e != nil {
+			invalid = append(invalid, fmt.Errorf("ptt line %d: %w", lineNums[i], e))
+		}
+	}
+	return
+}
+
+// readPtts reads all ptt records, and the line number of each record.
+func (p *PttFile) readPtts() (ptts []Ptt, lineNums []int, err error) {
 	rd := bufio.NewReader(p.r)
 	skipLines := 3
 	for i := 0; i < skipLines; i++ {
-		rd.ReadString('\n')
+		if _, err = rd.ReadString('\n'); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return
+		}
 	}
 
+	lineNum := skipLines
 	for {
 		line, err := rd.ReadString('\n')
-		if err != nil {
-			if err != io.EOF {
-				panic(err)
-			} else {
+		if err != nil && err != io.EOF {
+			return nil, nil, err
+		}
+		// the last line may not end with a newline,
+		// so parse it before breaking on EOF.
+		if line == "" && err == io.EOF {
+			break
+		}
+		lineNum++
+		line = strings.TrimSpace(line)
+		if line == "" {
+			// skip blank lines.
+			if err == io.EOF {
 				break
 			}
+			continue
 		}
-		line = strings.TrimSpace(line)
 		fields := strings.Split(line, "\t")
+		if len(fields) < 8 {
+			return nil, nil, fmt.Errorf("ptt line %d: expected 8 fields, got %d", lineNum, len(fields))
+		}
 		locTerms := strings.Split(fields[0], "..")
+		if len(locTerms) != 2 {
+			return nil, nil, fmt.Errorf("ptt line %d: malformed location %q", lineNum, fields[0])
+		}
 		start, _ := strconv.Atoi(locTerms[0])
 		end, _ := strconv.Atoi(locTerms[1])
 		strand := fields[1]
 		ptt := Ptt{}
 		ptt.Loc = Location{From: start, To: end, Strand: strand}
-		ptt.PID, ptt.Gene, ptt.SynonymCode, ptt.COG, ptt.Product =
-			fields[3], fields[4], fields[5], fields[6], fields[7]
+		ptt.Length, _ = strconv.Atoi(fields[2])
+		if len(fields) >= 9 {
+			// the standard columns have separate Synonym and Code.
+			ptt.PID, ptt.Gene, ptt.SynonymCode, ptt.Code, ptt.COG, ptt.Product =
+				fields[3], fields[4], fields[5], fields[6], fields[7], fields[8]
+		} else {
+			ptt.PID, ptt.Gene, ptt.SynonymCode, ptt.COG, ptt.Product =
+				fields[3], fields[4], fields[5], fields[6], fields[7]
+		}
 		ptts = append(ptts, ptt)
+		lineNums = append(lineNums, lineNum)
+		if err == io.EOF {
+			break
+		}
 	}
 
 	return
 }
+
+// Validate returns an error if the location of the record is invalid:
+// a coordinate that is not positive, a start after the end,
+// or a strand that is not "+" or "-".
+// The segments of a joined location are validated too.
+func (ptt Ptt) Validate() error {
+	if err := ptt.Loc.validate(); err != nil {
+		return err
+	}
+	for _, seg := range ptt.Segments {
+		if err := seg.validate(); err != nil {
+			return fmt.Errorf("segment: %w", err)
+		}
+	}
+	return nil
+}
+
+func (loc Location) validate() error {
+	if loc.From < 1 || loc.To < 1 {
+		return fmt.Errorf("invalid location %d..%d", loc.From, loc.To)
+	}
+	if loc.From > loc.To {
+		return fmt.Errorf("location %d..%d starts after its end", loc.From, loc.To)
+	}
+	if loc.Strand != "+" && loc.Strand != "-" {
+		return fmt.Errorf("invalid strand %q", loc.Strand)
+	}
+	return nil
+}
+
+// PttWriter writes ptt records in the .ptt format.
+// Since the header contains the number of records,
+// the records are buffered until Flush.
+type PttWriter struct {
+	w     io.Writer
+	title string
+	ptts  []Ptt
+}
+
+// NewPttWriter returns a writer of a .ptt file,
+// whose first header line is the title, e.g.
+// "Escherichia coli str. K-12 substr. MG1655 chromosome, complete genome - 1..4641652".
+func NewPttWriter(w io.Writer, title string) *PttWriter {
+	return &PttWriter{w: w, title: title}
+}
+
+// Write adds a ptt record.
+func (p *PttWriter) Write(ptt Ptt) error {
+	p.ptts = append(p.ptts, ptt)
+	return nil
+}
+
+// Flush writes the header and all records.
+func (p *PttWriter) Flush() error {
+	bw := bufio.NewWriter(p.w)
+	fmt.Fprintf(bw, "%s\n", p.title)
+	fmt.Fprintf(bw, "%d proteins\n", len(p.ptts))
+	fmt.Fprintf(bw, "Location\tStrand\tLength\tPID\tGene\tSynonym\tCode\tCOG\tProduct\n")
+	for _, ptt := range p.ptts {
+		length := ptt.Length
+		if length == 0 && ptt.Loc.To >= ptt.Loc.From {
+			// number of amino acids, excluding the stop codon.
+			length = (ptt.Loc.To-ptt.Loc.From+1)/3 - 1
+		}
+		fields := []string{
+			fmt.Sprintf("%d..%d", ptt.Loc.From, ptt.Loc.To),
+			ptt.Loc.Strand,
+			strconv.Itoa(length),
+			ptt.PID,
+			ptt.Gene,
+			ptt.SynonymCode,
+			orDash(ptt.Code),
+			orDash(ptt.COG),
+			ptt.Product,
+		}
+		fmt.Fprintf(bw, "%s\n", strings.Join(fields, "\t"))
+	}
+	p.ptts = nil
+
+	return bw.Flush()
+}
+
+// orDash returns "-" for an empty column.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
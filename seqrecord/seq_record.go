@@ -2,7 +2,6 @@ package seqrecord
 
 import (
 	"log"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -101,7 +100,7 @@ func ReadSeqRecords(genome, dir string, gc string) SeqRecords {
 }
 
 func readFasta(fileName string) []*seq.Sequence {
-	f, err := os.Open(fileName)
+	f, err := Open(fileName)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,144 @@
+package taxonomy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// LoadGeneticCodes reads genetic codes from the NCBI gc.prt file
+// (ftp://ftp.ncbi.nih.gov/entrez/misc/data/gc.prt),
+// and returns a map[id]GeneticCode.
+// The four-fold codons are computed from the translate tables.
+func LoadGeneticCodes(r io.Reader) (gcMap map[int]*GeneticCode, err error) {
+	tokens, err := tokenizePrt(r)
+	if err != nil {
+		return nil, err
+	}
+
+	gcMap = make(map[int]*GeneticCode)
+	depth := 0
+	var names []string
+	var id, cde, starts string
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t {
+		case "{":
+			depth++
+			if depth == 2 {
+				names, id, cde, starts = nil, "", "", ""
+			}
+		case "}":
+			if depth == 2 {
+				gc, err := newGeneticCode(names, id, cde, starts)
+				if err != nil {
+					return nil, err
+				}
+				n, _ := strconv.Atoi(gc.Id)
+				gcMap[n] = gc
+			}
+			depth--
+		case "name", "id", "ncbieaa", "sncbieaa":
+			if i+1 >= len(tokens) {
+				return nil, fmt.Errorf("gc.prt: missing value of %s", t)
+			}
+			i++
+			v := strings.Trim(tokens[i], "\"")
+			switch t {
+			case "name":
+				// a long name is wrapped across lines.
+				names = append(names, strings.Join(strings.Fields(v), " "))
+			case "id":
+				id = v
+			case "ncbieaa":
+				cde = v
+			case "sncbieaa":
+				starts = v
+			}
+		}
+	}
+
+	return
+}
+
+// newGeneticCode builds a genetic code from a table of gc.prt.
+func newGeneticCode(names []string, id, cde, starts string) (*GeneticCode, error) {
+	if _, err := strconv.Atoi(id); err != nil {
+		return nil, fmt.Errorf("gc.prt: invalid table id %q", id)
+	}
+	if len(cde) != 64 || len(starts) != 64 {
+		return nil, fmt.Errorf("gc.prt: table %s: expected 64 codons", id)
+	}
+
+	gc := GeneticCode{Id: id}
+	// the first name is the full name,
+	// and the second, if any, is the abbreviation.
+	if len(names) > 0 {
+		gc.Name = names[0]
+	}
+	if len(names) > 1 {
+		gc.Abbreviation = names[1]
+	}
+	gc.Table, gc.FFCodons = getTables(cde)
+	gc.Starts = getStartCodons(starts)
+	return &gc, nil
+}
+
+// tokenizePrt splits the ASN.1 text of gc.prt into tokens,
+// keeping quoted strings as single tokens and removing "--" comments.
+func tokenizePrt(r io.Reader) (tokens []string, err error) {
+	rd := bufio.NewReader(r)
+	var token []byte
+	inString := false
+	flush := func() {
+		if len(token) > 0 {
+			tokens = append(tokens, string(token))
+			token = nil
+		}
+	}
+
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		for i := 0; i < len(line); i++ {
+			c := line[i]
+			switch {
+			case inString:
+				token = append(token, c)
+				if c == '"' {
+					inString = false
+					flush()
+				}
+			case c == '"':
+				flush()
+				inString = true
+				token = append(token, c)
+			case c == '-' && i+1 < len(line) && line[i+1] == '-':
+				// a comment until the end of the line.
+				flush()
+				i = len(line)
+			case c == '{' || c == '}' || c == ',':
+				flush()
+				if c != ',' {
+					tokens = append(tokens, string(c))
+				}
+			case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+				flush()
+			default:
+				token = append(token, c)
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+	flush()
+
+	return
+}
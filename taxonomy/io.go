@@ -222,7 +222,103 @@ func getNames(f io.Reader) map[string]string {
 }
 
 // IsFourFold return true is the codon belong to four-fold degenerated.
+// The codon may be in lowercase, e.g. soft-masked;
+// it returns false if the codon is not three bases of A, C, G and T.
 func (gc GeneticCode) IsFourFold(codon string) bool {
 	codon = strings.ToUpper(codon)
+	if !isCodon(codon) {
+		return false
+	}
 	return gc.FFCodons[codon]
 }
+
+// isCodon returns true if the uppercase codon is three bases of A, C, G and T.
+func isCodon(codon string) bool {
+	if len(codon) != 3 {
+		return false
+	}
+	for i := 0; i < len(codon); i++ {
+		switch codon[i] {
+		case 'A', 'C', 'G', 'T':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// IsStartCodon returns true if the codon is a start codon of the genetic code.
+func (gc GeneticCode) IsStartCodon(codon string) bool {
+	codon = strings.ToUpper(codon)
+	for _, c := range gc.Starts {
+		if c == codon {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStopCodon returns true if the codon is a stop codon of the genetic code.
+func (gc GeneticCode) IsStopCodon(codon string) bool {
+	return gc.Table[strings.ToUpper(codon)] == '*'
+}
+
+// Degeneracy returns the number of synonymous substitutions (0 to 3)
+// at the position (0, 1 or 2) of the codon,
+// or -1 if the codon is not in the translate table.
+func (gc GeneticCode) Degeneracy(codon string, pos int) int {
+	codon = strings.ToUpper(codon)
+	aa, found := gc.Table[codon]
+	if !found || pos < 0 || pos > 2 {
+		return -1
+	}
+
+	n := 0
+	for _, b := range []byte("TCAG") {
+		if b == codon[pos] {
+			continue
+		}
+		c := []byte(codon)
+		c[pos] = b
+		if gc.Table[string(c)] == aa {
+			n++
+		}
+	}
+	return n
+}
+
+// Translate translates a nucleotide sequence into its amino acid sequence.
+// Codons with ambiguous bases are translated as 'X', and stop codons as '*'.
+// Trailing bases of a partial codon are ignored.
+func (gc GeneticCode) Translate(nucl []byte) []byte {
+	prot := make([]byte, 0, len(nucl)/3)
+	for i := 0; i+3 <= len(nucl); i += 3 {
+		aa, found := gc.Table[strings.ToUpper(string(nucl[i:i+3]))]
+		if !found {
+			aa = 'X'
+		}
+		prot = append(prot, aa)
+	}
+	return prot
+}
+
+// CodonSites returns the numbers of synonymous and non-synonymous sites of the codon,
+// as in Nei and Gojobori (1986): at each of the three positions,
+// the fraction of the three possible substitutions that are synonymous
+// is added to the synonymous sites, and the rest to the non-synonymous sites.
+// It returns zeros if the codon is not in the translate table, or is a stop codon,
+// which is not counted as in Nei and Gojobori (1986).
+func (gc GeneticCode) CodonSites(codon string) (synSites, nonSynSites float64) {
+	if gc.IsStopCodon(codon) {
+		return 0, 0
+	}
+	for pos := 0; pos < 3; pos++ {
+		n := gc.Degeneracy(codon, pos)
+		if n < 0 {
+			return 0, 0
+		}
+		synSites += float64(n) / 3
+	}
+	nonSynSites = 3 - synSites
+	return
+}
@@ -0,0 +1,141 @@
+package taxonomy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TaxNode is a node of the NCBI taxonomy tree, read from nodes.dmp.
+type TaxNode struct {
+	Id              int    // node id in GenBank taxonomy database
+	Parent          int    // parent node id, the root is its own parent
+	Rank            string // rank of this node (superkingdom, phylum, genus ...)
+	Name            string // scientific name, read from names.dmp
+	Division        string // division id
+	GeneticCode     string // genetic code id
+	MitochondrialGC string // mitochondrial genetic code id
+}
+
+// Nodes is the NCBI taxonomy tree, keyed by node id.
+type Nodes map[int]*TaxNode
+
+// LoadNodes reads the taxonomy tree from the NCBI nodes.dmp file
+// (ftp://ftp.ncbi.nih.gov/pub/taxonomy/taxdump.tar.gz).
+func LoadNodes(r io.Reader) (nodes Nodes, err error) {
+	nodes = make(Nodes)
+	err = readDmp(r, "nodes.dmp", 9, func(fields []string) error {
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return err
+		}
+		parent, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return err
+		}
+		nodes[id] = &TaxNode{
+			Id:              id,
+			Parent:          parent,
+			Rank:            fields[2],
+			Division:        fields[4],
+			GeneticCode:     fields[6],
+			MitochondrialGC: fields[8],
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// LoadNames reads the scientific names of the nodes from the NCBI names.dmp file.
+// Names of ids not in the nodes are ignored.
+func (nodes Nodes) LoadNames(r io.Reader) error {
+	return readDmp(r, "names.dmp", 4, func(fields []string) error {
+		if fields[3] != "scientific name" {
+			return nil
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return err
+		}
+		if n, found := nodes[id]; found {
+			n.Name = fields[1]
+		}
+		return nil
+	})
+}
+
+// Lineage returns the nodes from the root to the taxon,
+// or nil if the taxon is not in the tree.
+func (nodes Nodes) Lineage(taxid int) (lineage []TaxNode) {
+	for n, found := nodes[taxid]; found; n, found = nodes[n.Parent] {
+		lineage = append(lineage, *n)
+		// the root is its own parent,
+		// and a malformed tree may have a cycle.
+		if n.Parent == n.Id || len(lineage) > len(nodes) {
+			break
+		}
+	}
+
+	for i, j := 0, len(lineage)-1; i < j; i, j = i+1, j-1 {
+		lineage[i], lineage[j] = lineage[j], lineage[i]
+	}
+	return
+}
+
+// GeneticCodeForTaxID returns the genetic code of the taxon, e.g. table 4 for Mycoplasma,
+// from the genetic code id of its node and the default genetic codes (see GeneticCodes).
+// It returns an error if the taxon is not in the tree or its genetic code is unknown.
+// The genetic codes are shared by all the calls, and must not be modified.
+func (nodes Nodes) GeneticCodeForTaxID(taxid int) (*GeneticCode, error) {
+	n, found := nodes[taxid]
+	if !found {
+		return nil, fmt.Errorf("taxonomy: unknown taxid %d", taxid)
+	}
+	defaultCodesOnce.Do(func() { defaultCodes = GeneticCodes() })
+	gc, found := defaultCodes[n.GeneticCode]
+	if !found {
+		return nil, fmt.Errorf("taxonomy: taxid %d: unknown genetic code %q", taxid, n.GeneticCode)
+	}
+	return gc, nil
+}
+
+// the default genetic codes, parsed once by GeneticCodeForTaxID.
+var (
+	defaultCodesOnce sync.Once
+	defaultCodes     map[string]*GeneticCode
+)
+
+// readDmp calls fn with the fields of each line of a .dmp file,
+// which are delimited by "\t|\t" and end with "\t|".
+// It returns an error if a line has fewer than n fields.
+func readDmp(r io.Reader, name string, n int, fn func(fields []string) error) error {
+	rd := bufio.NewReader(r)
+	lineNum := 0
+	for {
+		l, err := rd.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if l != "" {
+			lineNum++
+			l = strings.TrimSuffix(strings.TrimRight(l, "\r\n"), "\t|")
+			fields := strings.Split(l, "\t|\t")
+			if len(fields) < n {
+				return fmt.Errorf("%s line %d: expected %d fields, got %d", name, lineNum, n, len(fields))
+			}
+			if err := fn(fields); err != nil {
+				return fmt.Errorf("%s line %d: %w", name, lineNum, err)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}